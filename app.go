@@ -3,11 +3,15 @@ package main
 import (
 	"context"
 	"fmt"
+	"os"
 	"sync"
+	"time"
 
+	"folder-cleaner-go/export"
 	"folder-cleaner-go/models"
 	"folder-cleaner-go/operations"
 	"folder-cleaner-go/scanner"
+	"folder-cleaner-go/selection"
 
 	"github.com/wailsapp/wails/v2/pkg/runtime"
 )
@@ -18,8 +22,23 @@ type App struct {
 	mu         sync.Mutex
 	scanning   bool
 	cancelScan context.CancelFunc
+	scanner    *scanner.Scanner // the running scan, if any
 	groups     []models.DuplicateGroup
+	stats      models.ScanStats
+	skipped    []models.SkippedFile
 	history    []models.DeleteOperation
+	undoing    map[string]bool // IDs of operations being undone
+	cache      *scanner.HashCache
+
+	// Undo bookkeeping for the current scan results: files taken out of
+	// groups per operation ID, and groups pruned for having one copy left.
+	removed map[string][]removedFile
+	dropped map[string]models.DuplicateGroup
+
+	// reference holds the ReferencePaths of the last scan, saved those of
+	// the persisted settings.
+	reference []string
+	saved     []string
 }
 
 // NewApp creates a new App application struct.
@@ -31,6 +50,13 @@ func NewApp() *App {
 // so we can call the runtime methods.
 func (a *App) startup(ctx context.Context) {
 	a.ctx = ctx
+
+	history, err := models.LoadJournal()
+	if err != nil {
+		runtime.LogErrorf(ctx, "load operation journal: %v", err)
+	}
+	a.history = history
+	a.saved = models.LoadSettings().ReferencePaths
 }
 
 // BuildInfo holds version and build metadata returned to the frontend.
@@ -70,11 +96,38 @@ func (a *App) GetSettings() models.ScanSettings {
 
 // SaveSettings persists the given scan settings to disk.
 func (a *App) SaveSettings(settings models.ScanSettings) error {
-	return models.SaveSettings(settings)
+	if err := models.SaveSettings(settings); err != nil {
+		return err
+	}
+	a.mu.Lock()
+	a.saved = settings.ReferencePaths
+	a.mu.Unlock()
+	return nil
 }
 
 // StartScan begins scanning the given directories for duplicates.
 func (a *App) StartScan(settings models.ScanSettings) error {
+	return a.startScan(settings, false)
+}
+
+// StartScanFromCheckpoint starts a scan that carries on from the checkpoint
+// of an unfinished scan with the same settings, or starts over if there is
+// none.
+func (a *App) StartScanFromCheckpoint(settings models.ScanSettings) error {
+	return a.startScan(settings, true)
+}
+
+// GetScanCheckpoint describes the saved progress of an unfinished scan with
+// these settings, or returns nil if there is none to resume.
+func (a *App) GetScanCheckpoint(settings models.ScanSettings) *models.ScanCheckpoint {
+	dir, err := scanner.DefaultCheckpointDir()
+	if err != nil {
+		return nil
+	}
+	return scanner.FindCheckpoint(dir, settings)
+}
+
+func (a *App) startScan(settings models.ScanSettings, resume bool) error {
 	a.mu.Lock()
 	if a.scanning {
 		a.mu.Unlock()
@@ -82,8 +135,21 @@ func (a *App) StartScan(settings models.ScanSettings) error {
 	}
 	a.scanning = true
 	a.groups = nil
+	a.stats = models.ScanStats{}
+	a.skipped = nil
+	a.removed = nil
+	a.dropped = nil
+	a.reference = settings.ReferencePaths
 	ctx, cancel := context.WithCancel(a.ctx)
 	a.cancelScan = cancel
+	s := scanner.New(settings, func(p models.ScanProgress) {
+		runtime.EventsEmit(a.ctx, "scan:progress", p)
+	})
+	s.SetCache(a.hashCache())
+	if dir, err := scanner.DefaultCheckpointDir(); err == nil {
+		s.SetCheckpoint(dir, resume)
+	}
+	a.scanner = s
 	a.mu.Unlock()
 
 	go func() {
@@ -91,17 +157,10 @@ func (a *App) StartScan(settings models.ScanSettings) error {
 			a.mu.Lock()
 			a.scanning = false
 			a.cancelScan = nil
+			a.scanner = nil
 			a.mu.Unlock()
 		}()
 
-		s := scanner.New(settings, func(stage string, processed, total int) {
-			runtime.EventsEmit(a.ctx, "scan:progress", map[string]interface{}{
-				"stage":     stage,
-				"processed": processed,
-				"total":     total,
-			})
-		})
-
 		groups, err := s.Run(ctx)
 		if err != nil {
 			if ctx.Err() != nil {
@@ -114,6 +173,8 @@ func (a *App) StartScan(settings models.ScanSettings) error {
 
 		a.mu.Lock()
 		a.groups = groups
+		a.stats = s.Stats()
+		a.skipped = s.Skipped()
 		a.mu.Unlock()
 
 		count := 0
@@ -135,6 +196,74 @@ func (a *App) CancelScan() {
 	}
 }
 
+// PauseScan suspends the running scan without losing its progress. Reads
+// already in flight finish their current chunk, then wait for ResumeScan.
+func (a *App) PauseScan() {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.scanner != nil {
+		a.scanner.Pause()
+	}
+}
+
+// ResumeScan continues a paused scan.
+func (a *App) ResumeScan() {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.scanner != nil {
+		a.scanner.Resume()
+	}
+}
+
+// SetReadRateLimit changes the read rate limit of the running scan, in bytes
+// per second (0 is unlimited). It does nothing when no scan is running; the
+// next scan takes its limit from its settings.
+func (a *App) SetReadRateLimit(bytesPerSecond int64) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.scanner != nil {
+		a.scanner.SetReadRate(bytesPerSecond)
+	}
+}
+
+// hashCache lazily opens the persistent hash cache. Returns nil (caching
+// disabled) if no cache location is available. Callers must hold a.mu.
+func (a *App) hashCache() *scanner.HashCache {
+	if a.cache == nil {
+		p, err := scanner.DefaultHashCachePath()
+		if err != nil {
+			return nil
+		}
+		a.cache = scanner.OpenHashCache(p)
+	}
+	return a.cache
+}
+
+// GetHashCacheStats reports the number of cached files and the cache size on disk.
+func (a *App) GetHashCacheStats() models.CacheStats {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	c := a.hashCache()
+	if c == nil {
+		return models.CacheStats{}
+	}
+	return c.Stats()
+}
+
+// ClearHashCache deletes all cached hashes so the next scan rehashes everything.
+func (a *App) ClearHashCache() error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.scanning {
+		return fmt.Errorf("cannot clear cache while a scan is in progress")
+	}
+	c := a.hashCache()
+	if c == nil {
+		return nil
+	}
+	return c.Clear()
+}
+
 // GetDuplicateGroups returns the current duplicate groups found.
 func (a *App) GetDuplicateGroups() []models.DuplicateGroup {
 	a.mu.Lock()
@@ -142,48 +271,295 @@ func (a *App) GetDuplicateGroups() []models.DuplicateGroup {
 	return a.groups
 }
 
+// GetScanStats returns counts from the last completed scan, such as how many
+// images could not be decoded for perceptual hashing.
+func (a *App) GetScanStats() models.ScanStats {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return a.stats
+}
+
+// GetSkippedFiles returns the files and directories the last completed scan
+// could not examine, such as unreadable or undecodable files, with the reason
+// for each.
+func (a *App) GetSkippedFiles() []models.SkippedFile {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return a.skipped
+}
+
+// ProposeSelection applies the ordered keep rules to the current groups and
+// returns, per group, which file would be kept and which removed. Nothing is
+// deleted; pass the confirmed Remove paths to DeleteFiles.
+func (a *App) ProposeSelection(rules []models.SelectionRule) ([]models.Selection, error) {
+	a.mu.Lock()
+	groups := a.groups
+	a.mu.Unlock()
+	return selection.Propose(groups, rules)
+}
+
+// ExportResults asks where to save and writes the current duplicate groups,
+// and the files the scan skipped, as a JSON, CSV or HTML report. Returns the
+// saved path, or "" if cancelled.
+func (a *App) ExportResults(format string) (string, error) {
+	f, err := export.ParseFormat(format)
+	if err != nil {
+		return "", err
+	}
+
+	path, err := runtime.SaveFileDialog(a.ctx, runtime.SaveDialogOptions{
+		Title:           "Export Results",
+		DefaultFilename: "shadowwipe-report" + f.Extension(),
+	})
+	if err != nil || path == "" {
+		return "", err
+	}
+
+	a.mu.Lock()
+	report := export.NewReport(a.groups, a.skipped)
+	a.mu.Unlock()
+
+	out, err := os.Create(path)
+	if err != nil {
+		return "", err
+	}
+	if err := export.Write(out, f, report); err != nil {
+		out.Close()
+		return "", err
+	}
+	return path, out.Close()
+}
+
 // DeleteFiles moves the specified files to trash and records the operation.
+// Files under a reference path are refused and reported as failures.
 func (a *App) DeleteFiles(paths []string) (*models.DeleteOperation, error) {
-	op, err := operations.MoveToTrash(paths)
+	a.mu.Lock()
+	protected := a.protectedPaths()
+	a.mu.Unlock()
+
+	op, err := operations.MoveToTrash(paths, protected)
 	if err != nil {
 		return nil, err
 	}
 
 	a.mu.Lock()
-	a.history = append(a.history, *op)
-
+	op.Removals = a.describeRemovals(op.DeletedPaths, "")
+	a.record(op)
 	// Remove only successfully trashed files from groups
-	trashedSet := make(map[string]bool, len(op.DeletedPaths))
-	for _, p := range op.DeletedPaths {
-		trashedSet[p] = true
+	a.removeFromGroups(op.ID, op.DeletedPaths)
+	a.mu.Unlock()
+
+	return op, nil
+}
+
+// ReplaceWithHardLinks replaces the given duplicates in an exact group with
+// hard links to keepPath and records the operation. keepPath may not be a
+// reference file: a write through any of the links would change it.
+func (a *App) ReplaceWithHardLinks(groupID, keepPath string, paths []string) (*models.DeleteOperation, error) {
+	a.mu.Lock()
+	group, err := a.exactGroup(groupID, keepPath, paths)
+	protected := a.protectedPaths()
+	a.mu.Unlock()
+	if err != nil {
+		return nil, err
 	}
-	filtered := a.groups[:0]
-	for _, g := range a.groups {
-		var remaining []models.FileInfo
-		for _, f := range g.Files {
-			if !trashedSet[f.Path] {
-				remaining = append(remaining, f)
-			}
+	for _, f := range group.Files {
+		if f.Path == keepPath && (f.ReadOnly || models.IsUnderAny(keepPath, protected)) {
+			return nil, fmt.Errorf("cannot hard-link to %s in a protected reference directory; keep a copy outside it", keepPath)
+		}
+	}
+
+	op, err := operations.ReplaceWithHardLinks(keepPath, paths)
+	if err != nil {
+		return nil, err
+	}
+
+	var keep models.FileInfo
+	for _, f := range group.Files {
+		if f.Path == keepPath {
+			keep = f
 		}
-		if len(remaining) > 1 {
-			g.Files = remaining
-			// Recalculate sizes
-			var totalSize int64
-			for _, f := range remaining {
-				totalSize += f.Size
+	}
+	linked := make(map[string]bool, len(op.Replaced))
+	replaced := make([]string, 0, len(op.Replaced))
+	for _, r := range op.Replaced {
+		linked[r.Path] = true
+		replaced = append(replaced, r.Path)
+	}
+
+	a.mu.Lock()
+	op.Removals = a.describeRemovals(replaced, keepPath)
+	a.record(op)
+
+	// Linked files now share the kept file's inode
+	for _, g := range a.groups {
+		for i := range g.Files {
+			if linked[g.Files[i].Path] {
+				g.Files[i].Device, g.Files[i].Inode = keep.Device, keep.Inode
 			}
-			g.TotalSize = totalSize
-			g.WastedSize = totalSize - remaining[0].Size
-			filtered = append(filtered, g)
 		}
 	}
-	a.groups = filtered
+	a.regroup()
 	a.mu.Unlock()
 
 	return op, nil
 }
 
-// GetOperationHistory returns past delete operations for undo support.
+// ReplaceWithSymlinks replaces the given duplicates in an exact group with
+// symbolic links to keepPath and records the operation. Originals go to the
+// system trash unless opts names a backup directory.
+func (a *App) ReplaceWithSymlinks(groupID, keepPath string, paths []string, opts models.SymlinkOptions) (*models.DeleteOperation, error) {
+	a.mu.Lock()
+	_, err := a.exactGroup(groupID, keepPath, paths)
+	a.mu.Unlock()
+	if err != nil {
+		return nil, err
+	}
+
+	op, err := operations.ReplaceWithSymlinks(keepPath, paths, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	replaced := make([]string, 0, len(op.Replaced))
+	for _, r := range op.Replaced {
+		replaced = append(replaced, r.Path)
+	}
+
+	a.mu.Lock()
+	op.Removals = a.describeRemovals(replaced, keepPath)
+	a.record(op)
+	// Symlinks are not scanned, so replaced paths leave their groups
+	a.removeFromGroups(op.ID, replaced)
+	a.mu.Unlock()
+
+	return op, nil
+}
+
+// DedupeFiles makes the given duplicates in an exact group share storage with
+// keepPath via copy-on-write extents (Btrfs, XFS) and records the operation.
+// Every path remains an independent file, so nothing needs undoing.
+func (a *App) DedupeFiles(groupID, keepPath string, paths []string) (*models.DeleteOperation, error) {
+	a.mu.Lock()
+	_, err := a.exactGroup(groupID, keepPath, paths)
+	a.mu.Unlock()
+	if err != nil {
+		return nil, err
+	}
+
+	op, err := operations.DedupeFiles(keepPath, paths)
+	if err != nil {
+		return nil, err
+	}
+
+	deduped := make([]string, 0, len(op.Replaced))
+	for _, r := range op.Replaced {
+		deduped = append(deduped, r.Path)
+	}
+
+	a.mu.Lock()
+	op.Removals = a.describeRemovals(deduped, keepPath)
+	a.record(op)
+	// Shared extents waste no space, so deduplicated paths leave their groups
+	a.removeFromGroups(op.ID, deduped)
+	a.mu.Unlock()
+
+	return op, nil
+}
+
+// UndoOperation reverts a recorded operation and puts the restored files
+// back into their duplicate groups. Only the paths actually restored are
+// marked as undone, so an undo that partly failed can be retried for the
+// rest; the operation is marked undone once every path has been restored.
+func (a *App) UndoOperation(id string) (*models.UndoResult, error) {
+	a.mu.Lock()
+	op, ok := a.operation(id)
+	switch {
+	case !ok:
+		a.mu.Unlock()
+		return nil, fmt.Errorf("operation not found")
+	case op.Undone:
+		a.mu.Unlock()
+		return nil, fmt.Errorf("operation already undone")
+	case a.undoing[id]:
+		a.mu.Unlock()
+		return nil, fmt.Errorf("operation is already being undone")
+	}
+	if a.undoing == nil {
+		a.undoing = make(map[string]bool)
+	}
+	a.undoing[id] = true
+	snapshot := *op
+	a.mu.Unlock()
+
+	result, err := operations.Undo(snapshot)
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	delete(a.undoing, id)
+	if err != nil {
+		return nil, err
+	}
+	restored := result.Originals()
+	if len(restored) == 0 {
+		return result, nil
+	}
+	if op, ok := a.operation(id); ok {
+		op.MarkUndone(restored)
+	}
+	a.restoreToGroups(id, result)
+	a.journal(models.JournalEntry{Event: models.JournalUndo, OperationID: id, Paths: restored})
+
+	return result, nil
+}
+
+// operation returns the recorded operation with the given ID. Callers must
+// hold a.mu.
+func (a *App) operation(id string) (*models.DeleteOperation, bool) {
+	for i := range a.history {
+		if a.history[i].ID == id {
+			return &a.history[i], true
+		}
+	}
+	return nil, false
+}
+
+// protectedPaths returns the reference roots of the last scan together with
+// those currently saved, so a settings change cannot expose scanned files.
+// Callers must hold a.mu.
+func (a *App) protectedPaths() []string {
+	return append(append([]string{}, a.reference...), a.saved...)
+}
+
+// record adds op to the in-memory history and the persistent journal.
+// Callers must hold a.mu.
+func (a *App) record(op *models.DeleteOperation) {
+	a.history = append(a.history, *op)
+	a.journal(models.JournalEntry{Event: models.JournalOperation, Operation: op})
+}
+
+// journal appends e to the operation journal. The files have already been
+// changed by then, so a write failure is logged rather than failing the call.
+func (a *App) journal(e models.JournalEntry) {
+	e.Timestamp = time.Now().UTC().Format(time.RFC3339)
+	if err := models.AppendJournal(e); err != nil {
+		runtime.LogErrorf(a.ctx, "write operation journal: %v", err)
+	}
+}
+
+// QueryJournal returns recorded operations filtered by date range and path
+// prefix, oldest first. It reads the on-disk journal, so operations from
+// earlier sessions are included.
+func (a *App) QueryJournal(filter models.JournalFilter) ([]models.DeleteOperation, error) {
+	ops, err := models.LoadJournal()
+	if err != nil {
+		return nil, err
+	}
+	return filter.Filter(ops)
+}
+
+// GetOperationHistory returns past operations, including those from earlier
+// sessions, for undo support.
 func (a *App) GetOperationHistory() []models.DeleteOperation {
 	a.mu.Lock()
 	defer a.mu.Unlock()
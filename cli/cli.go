@@ -0,0 +1,73 @@
+// Package cli implements ShadowWipe's headless command-line mode, which runs
+// the scan pipeline without the desktop UI (for servers and cron jobs).
+package cli
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"syscall"
+)
+
+// Exit codes returned by Run.
+const (
+	ExitOK         = 0   // scan completed, no duplicates found
+	ExitDuplicates = 1   // scan completed, duplicates found
+	ExitUsage      = 2   // invalid command line
+	ExitError      = 3   // scan failed
+	ExitCancelled  = 130 // interrupted by SIGINT/SIGTERM
+)
+
+// commands maps subcommand names to their implementations.
+var commands = map[string]func(ctx context.Context, args []string, stdout, stderr io.Writer) int{
+	"scan": runScan,
+}
+
+// IsCommand reports whether name is a CLI subcommand, letting the desktop
+// entry point decide between headless mode and launching the UI.
+func IsCommand(name string) bool {
+	if name == "help" || name == "-h" || name == "--help" {
+		return true
+	}
+	_, ok := commands[name]
+	return ok
+}
+
+// Main runs the CLI with the process arguments (excluding the program name)
+// and standard streams, cancelling the scan on SIGINT or SIGTERM.
+func Main(args []string) int {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	return Run(ctx, args, os.Stdout, os.Stderr)
+}
+
+// Run dispatches args[0] to the matching subcommand and returns its exit code.
+func Run(ctx context.Context, args []string, stdout, stderr io.Writer) int {
+	if len(args) == 0 {
+		usage(stderr)
+		return ExitUsage
+	}
+
+	cmd, ok := commands[args[0]]
+	if !ok {
+		if IsCommand(args[0]) {
+			usage(stdout)
+			return ExitOK
+		}
+		fmt.Fprintf(stderr, "unknown command %q\n\n", args[0])
+		usage(stderr)
+		return ExitUsage
+	}
+	return cmd(ctx, args[1:], stdout, stderr)
+}
+
+func usage(w io.Writer) {
+	fmt.Fprintln(w, "Usage: shadowwipe <command> [flags]")
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "Commands:")
+	fmt.Fprintln(w, "  scan    Find duplicate files and print the groups")
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "Run 'shadowwipe <command> -h' for command flags.")
+}
@@ -0,0 +1,393 @@
+package cli
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"folder-cleaner-go/export"
+	"folder-cleaner-go/models"
+	"folder-cleaner-go/scanner"
+)
+
+// stringList is a repeatable string flag.
+type stringList []string
+
+func (l *stringList) String() string { return strings.Join(*l, ",") }
+
+func (l *stringList) Set(v string) error {
+	*l = append(*l, v)
+	return nil
+}
+
+// runScan implements `shadowwipe scan`.
+func runScan(ctx context.Context, args []string, stdout, stderr io.Writer) int {
+	defaults := models.DefaultSettings()
+
+	fs := flag.NewFlagSet("scan", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	var (
+		paths         stringList
+		excludes      stringList
+		references    stringList
+		minSize       = fs.String("min-size", "0", "ignore files smaller than this (e.g. 512, 100KB, 5MB; bare numbers are KB)")
+		threshold     = fs.Float64("threshold", defaults.SimilarityThreshold, "perceptual hash Hamming distance in bits for similar images (0 disables)")
+		includeHidden = fs.Bool("include-hidden", !defaults.SkipHidden, "scan hidden files and directories")
+		noDefaultExcl = fs.Bool("no-default-excludes", false, "do not skip the default excluded directories")
+		hashAlgo      = fs.String("hash", string(models.HashPerception), "perceptual hash: average, difference, perception or extended (256-bit)")
+		cluster       = fs.String("cluster", string(models.ClusterGreedy), "similar image grouping: greedy, connected or complete")
+		transforms    = fs.Bool("transforms", defaults.MatchTransforms, "also match rotated and mirrored copies of similar images")
+		ioLimits      = fs.String("io-limits", "", "concurrent reads per device by type, e.g. rotational=1,ssd=8,nvme=32,unknown=4")
+		maxRate       = fs.String("max-rate", "0", "cap the combined read rate in bytes per second (e.g. 500KB, 50MB, 1GB; bare numbers are MB; 0 is unlimited)")
+		idle          = fs.Bool("idle", defaults.IdleIOPriority, "read at idle I/O priority, yielding the disk to other programs (Linux)")
+		format        = fs.String("format", "text", "output format: text, json, csv or html")
+		output        = fs.String("output", "", "write results to this file instead of stdout")
+		noCache       = fs.Bool("no-cache", false, "ignore and do not update the hash cache or save a checkpoint")
+		resume        = fs.Bool("resume", false, "carry on from the checkpoint of an interrupted scan with the same settings")
+		quiet         = fs.Bool("quiet", false, "do not print progress to stderr")
+	)
+	fs.Var(&paths, "path", "directory to scan (repeatable; positional arguments are also accepted)")
+	fs.Var(&excludes, "exclude", "directory name to skip (repeatable)")
+	fs.Var(&references, "reference", "read-only directory whose files are matched but never reported for removal (repeatable)")
+	fs.Usage = func() {
+		fmt.Fprintln(stderr, "Usage: shadowwipe scan [flags] [path ...]")
+		fmt.Fprintln(stderr)
+		fs.PrintDefaults()
+	}
+
+	if err := fs.Parse(args); err != nil {
+		if err == flag.ErrHelp {
+			return ExitOK
+		}
+		return ExitUsage
+	}
+	paths = append(paths, fs.Args()...)
+
+	if len(paths) == 0 {
+		fmt.Fprintln(stderr, "scan: at least one --path is required")
+		return ExitUsage
+	}
+	if *format != "text" {
+		if _, err := export.ParseFormat(*format); err != nil {
+			fmt.Fprintf(stderr, "scan: %v\n", err)
+			return ExitUsage
+		}
+	}
+	switch models.ClusterMode(*cluster) {
+	case models.ClusterGreedy, models.ClusterConnected, models.ClusterComplete:
+	default:
+		fmt.Fprintf(stderr, "scan: unknown --cluster mode %q\n", *cluster)
+		return ExitUsage
+	}
+	switch models.HashAlgorithm(*hashAlgo) {
+	case models.HashAverage, models.HashDifference, models.HashPerception, models.HashExtended:
+	default:
+		fmt.Fprintf(stderr, "scan: unknown --hash algorithm %q\n", *hashAlgo)
+		return ExitUsage
+	}
+	if *threshold < 0 {
+		fmt.Fprintln(stderr, "scan: --threshold must not be negative")
+		return ExitUsage
+	}
+	limits, err := parseIOLimits(*ioLimits, defaults.IOLimits)
+	if err != nil {
+		fmt.Fprintf(stderr, "scan: --io-limits: %v\n", err)
+		return ExitUsage
+	}
+	rate, err := parseRate(*maxRate)
+	if err != nil {
+		fmt.Fprintf(stderr, "scan: --max-rate: %v\n", err)
+		return ExitUsage
+	}
+
+	settings := defaults
+	settings.Paths = paths
+	settings.ReferencePaths = references
+	settings.SimilarityThreshold = *threshold
+	settings.ClusterMode = models.ClusterMode(*cluster)
+	settings.HashAlgorithm = models.HashAlgorithm(*hashAlgo)
+	settings.MatchTransforms = *transforms
+	settings.IOLimits = limits
+	settings.MaxReadRate = rate
+	settings.IdleIOPriority = *idle
+	settings.SkipHidden = !*includeHidden
+	if *noDefaultExcl {
+		settings.ExcludedDirs = []string{}
+	}
+	settings.ExcludedDirs = append(settings.ExcludedDirs, excludes...)
+
+	size, unit, err := parseMinSize(*minSize)
+	if err != nil {
+		fmt.Fprintf(stderr, "scan: --min-size: %v\n", err)
+		return ExitUsage
+	}
+	settings.MinFileSize, settings.MinFileSizeUnit = size, unit
+
+	var last models.ScanProgress
+	var lastAt time.Time
+	s := scanner.New(settings, func(p models.ScanProgress) {
+		// Hashing reports arrive several times a second; print at most one a
+		// second unless the stage changes or it finishes.
+		if p.Stage == last.Stage && p.Paused == last.Paused && p.Processed < p.Total &&
+			time.Since(lastAt) < time.Second {
+			return
+		}
+		last, lastAt = p, time.Now()
+		switch {
+		case *quiet:
+		case p.Paused:
+			fmt.Fprintf(stderr, "%s: paused (send SIGCONT or use fg/bg to resume)\n", p.Stage)
+		case p.Total > 0 && p.BytesTotal > 0:
+			fmt.Fprintf(stderr, "%s: %d/%d files, %s of %s%s\n", p.Stage, p.Processed, p.Total,
+				export.FormatSize(p.BytesDone), export.FormatSize(p.BytesTotal), formatRate(p))
+		case p.Total > 0:
+			fmt.Fprintf(stderr, "%s: %d/%d\n", p.Stage, p.Processed, p.Total)
+		default:
+			fmt.Fprintf(stderr, "%s...\n", p.Stage)
+		}
+	})
+	if !*noCache {
+		if p, err := scanner.DefaultHashCachePath(); err == nil {
+			s.SetCache(scanner.OpenHashCache(p))
+		}
+		if dir, err := scanner.DefaultCheckpointDir(); err == nil {
+			s.SetCheckpoint(dir, *resume)
+			if *resume && !*quiet {
+				if cp := scanner.FindCheckpoint(dir, settings); cp != nil {
+					fmt.Fprintf(stderr, "resuming interrupted scan (%d files hashed, stopped during %s)\n", cp.FilesHashed, cp.Stage)
+				} else {
+					fmt.Fprintln(stderr, "no checkpoint for these settings; starting a new scan")
+				}
+			}
+		}
+	} else if *resume {
+		fmt.Fprintln(stderr, "scan: --resume cannot be used with --no-cache")
+		return ExitUsage
+	}
+
+	stopWatching := watchSuspend(s)
+	groups, err := s.Run(ctx)
+	stopWatching()
+	if err != nil {
+		if ctx.Err() != nil {
+			fmt.Fprintln(stderr, "scan cancelled")
+			return ExitCancelled
+		}
+		fmt.Fprintf(stderr, "scan failed: %v\n", err)
+		return ExitError
+	}
+	if groups == nil {
+		groups = []models.DuplicateGroup{}
+	}
+	if n := s.Stats().UndecodableImages; n > 0 && !*quiet {
+		fmt.Fprintf(stderr, "%d images could not be decoded and were not compared for similarity\n", n)
+	}
+	skipped := s.Skipped()
+	if len(skipped) > 0 && !*quiet {
+		fmt.Fprintf(stderr, "%d files skipped (%s); see the report for the list\n", len(skipped), summariseSkipped(skipped))
+	}
+	sortGroups(groups)
+
+	if err := writeResults(stdout, *output, *format, export.NewReport(groups, skipped)); err != nil {
+		fmt.Fprintf(stderr, "write output: %v\n", err)
+		return ExitError
+	}
+
+	if len(groups) > 0 {
+		return ExitDuplicates
+	}
+	return ExitOK
+}
+
+// writeResults writes r in format to path, or to stdout if path is empty.
+func writeResults(stdout io.Writer, path, format string, r export.Report) error {
+	if path == "" {
+		return renderResults(stdout, format, r)
+	}
+
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := renderResults(f, format, r); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+func renderResults(w io.Writer, format string, r export.Report) error {
+	if format == "text" {
+		writeText(w, r.Groups, r.Skipped)
+		return nil
+	}
+	return export.Write(w, export.Format(format), r)
+}
+
+// parseMinSize accepts a number with an optional KB or MB suffix and returns
+// it in the MinFileSize/MinFileSizeUnit form used by ScanSettings. Bare
+// numbers are KB.
+func parseMinSize(input string) (int64, string, error) {
+	v := strings.ToUpper(strings.TrimSpace(input))
+	unit, mult := "KB", int64(1024)
+	switch {
+	case strings.HasSuffix(v, "MB"):
+		unit, mult, v = "MB", 1024*1024, strings.TrimSuffix(v, "MB")
+	case strings.HasSuffix(v, "KB"):
+		v = strings.TrimSuffix(v, "KB")
+	}
+	n, err := strconv.ParseInt(strings.TrimSpace(v), 10, 64)
+	if err != nil || n < 0 {
+		return 0, "", fmt.Errorf("invalid size %q", input)
+	}
+	if n > math.MaxInt64/mult {
+		return 0, "", fmt.Errorf("size %q is too large", input)
+	}
+	return n, unit, nil
+}
+
+// parseRate accepts a number with an optional KB, MB or GB suffix and returns
+// it in bytes. Bare numbers are MB.
+func parseRate(input string) (int64, error) {
+	v := strings.ToUpper(strings.TrimSpace(input))
+	mult := int64(1024 * 1024)
+	for _, u := range []struct {
+		suffix string
+		mult   int64
+	}{{"KB", 1024}, {"MB", 1024 * 1024}, {"GB", 1024 * 1024 * 1024}} {
+		if strings.HasSuffix(v, u.suffix) {
+			mult, v = u.mult, strings.TrimSuffix(v, u.suffix)
+			break
+		}
+	}
+	n, err := strconv.ParseInt(strings.TrimSpace(v), 10, 64)
+	if err != nil || n < 0 {
+		return 0, fmt.Errorf("invalid rate %q", input)
+	}
+	if n > math.MaxInt64/mult {
+		return 0, fmt.Errorf("rate %q is too large", input)
+	}
+	return n * mult, nil
+}
+
+// parseIOLimits applies a comma-separated list of type=count pairs, such as
+// "rotational=1,nvme=16", on top of base.
+func parseIOLimits(v string, base models.IOLimits) (models.IOLimits, error) {
+	limits := base
+	for _, pair := range strings.Split(v, ",") {
+		if strings.TrimSpace(pair) == "" {
+			continue
+		}
+		name, count, ok := strings.Cut(pair, "=")
+		n, err := strconv.Atoi(strings.TrimSpace(count))
+		if !ok || err != nil || n < 1 {
+			return base, fmt.Errorf("invalid limit %q", pair)
+		}
+		switch models.DeviceType(strings.TrimSpace(name)) {
+		case models.DeviceRotational:
+			limits.Rotational = n
+		case models.DeviceSSD:
+			limits.SSD = n
+		case models.DeviceNVMe:
+			limits.NVMe = n
+		case models.DeviceUnknown:
+			limits.Unknown = n
+		default:
+			return base, fmt.Errorf("unknown device type %q", name)
+		}
+	}
+	return limits, nil
+}
+
+// sortGroups orders groups by wasted space, largest first, so output is
+// stable across runs and the most valuable groups come first. Files in exact
+// groups are sorted by path; similar groups keep their representative first.
+func sortGroups(groups []models.DuplicateGroup) {
+	for i := range groups {
+		if groups[i].Kind == models.KindSimilar {
+			continue
+		}
+		files := groups[i].Files
+		sort.SliceStable(files, func(a, b int) bool { return files[a].Path < files[b].Path })
+		groups[i].Recalculate()
+	}
+	sort.SliceStable(groups, func(i, j int) bool {
+		if groups[i].WastedSize != groups[j].WastedSize {
+			return groups[i].WastedSize > groups[j].WastedSize
+		}
+		return groups[i].Files[0].Path < groups[j].Files[0].Path
+	})
+}
+
+func writeText(w io.Writer, groups []models.DuplicateGroup, skipped []models.SkippedFile) {
+	var wasted int64
+	for _, g := range groups {
+		wasted += g.WastedSize
+		switch g.Kind {
+		case models.KindSimilar:
+			fmt.Fprintf(w, "similar (%.1f%%, max distance %d), %d files, %s reclaimable\n", g.Similarity, g.MaxDistance, len(g.Files), export.FormatSize(g.WastedSize))
+		default:
+			fmt.Fprintf(w, "exact, %d files, %s reclaimable\n", len(g.Files), export.FormatSize(g.WastedSize))
+		}
+		for _, f := range g.Files {
+			switch {
+			case f.HardLinkOf != "":
+				fmt.Fprintf(w, "  %s (hard link of %s)\n", f.Path, f.HardLinkOf)
+			case f.ReadOnly:
+				fmt.Fprintf(w, "  %s (reference)\n", f.Path)
+			case g.Kind == models.KindSimilar && f.Transform != models.TransformNone:
+				fmt.Fprintf(w, "  %s (%.1f%%, distance %d, %s)\n", f.Path, f.Similarity, f.Distance, f.Transform.Label())
+			case g.Kind == models.KindSimilar:
+				fmt.Fprintf(w, "  %s (%.1f%%, distance %d)\n", f.Path, f.Similarity, f.Distance)
+			default:
+				fmt.Fprintf(w, "  %s\n", f.Path)
+			}
+		}
+		fmt.Fprintln(w)
+	}
+	fmt.Fprintf(w, "%d groups, %s reclaimable\n", len(groups), export.FormatSize(wasted))
+
+	if len(skipped) > 0 {
+		fmt.Fprintf(w, "\nskipped, %d files\n", len(skipped))
+		for _, f := range skipped {
+			fmt.Fprintf(w, "  %s (%s: %s)\n", f.Path, f.Reason.Label(), f.Error)
+		}
+	}
+}
+
+// summariseSkipped counts skipped files by reason, e.g.
+// "3 permission denied, 1 I/O error".
+func summariseSkipped(skipped []models.SkippedFile) string {
+	counts := make(map[models.SkipReason]int)
+	var order []models.SkipReason
+	for _, f := range skipped {
+		if counts[f.Reason] == 0 {
+			order = append(order, f.Reason)
+		}
+		counts[f.Reason]++
+	}
+	parts := make([]string, len(order))
+	for i, r := range order {
+		parts[i] = fmt.Sprintf("%d %s", counts[r], r.Label())
+	}
+	return strings.Join(parts, ", ")
+}
+
+// formatRate describes the throughput and ETA of a progress report, if known.
+func formatRate(p models.ScanProgress) string {
+	if p.Throughput <= 0 {
+		return ""
+	}
+	out := fmt.Sprintf(", %s/s", export.FormatSize(int64(p.Throughput)))
+	if p.ETA > 0 {
+		out += ", ETA " + (time.Duration(p.ETA) * time.Second).String()
+	}
+	return out
+}
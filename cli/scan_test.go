@@ -0,0 +1,190 @@
+package cli
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"folder-cleaner-go/models"
+)
+
+func TestParseMinSize(t *testing.T) {
+	tests := []struct {
+		in      string
+		n       int64
+		unit    string
+		wantErr string
+	}{
+		{"512", 512, "KB", ""},
+		{"100KB", 100, "KB", ""},
+		{" 5mb ", 5, "MB", ""},
+		{"0", 0, "KB", ""},
+		{"3MBB", 0, "", `invalid size "3MBB"`},
+		{"-1", 0, "", `invalid size "-1"`},
+		{"5GB", 0, "", `invalid size "5GB"`},
+		{"9223372036854775807MB", 0, "", `size "9223372036854775807MB" is too large`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.in, func(t *testing.T) {
+			n, unit, err := parseMinSize(tt.in)
+			if tt.wantErr != "" {
+				if err == nil || err.Error() != tt.wantErr {
+					t.Fatalf("err = %v, want %s", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if n != tt.n || unit != tt.unit {
+				t.Errorf("got %d %s, want %d %s", n, unit, tt.n, tt.unit)
+			}
+		})
+	}
+}
+
+func TestParseRate(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    int64
+		wantErr string
+	}{
+		{"0", 0, ""},
+		{"50", 50 << 20, ""},
+		{"500KB", 500 << 10, ""},
+		{"50mb", 50 << 20, ""},
+		{"1GB", 1 << 30, ""},
+		{"5XB", 0, `invalid rate "5XB"`},
+		{"fast", 0, `invalid rate "fast"`},
+		{"-5MB", 0, `invalid rate "-5MB"`},
+		{"8589934592GB", 0, `rate "8589934592GB" is too large`},
+		{"9223372036854775807", 0, `rate "9223372036854775807" is too large`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.in, func(t *testing.T) {
+			got, err := parseRate(tt.in)
+			if tt.wantErr != "" {
+				if err == nil || err.Error() != tt.wantErr {
+					t.Fatalf("err = %v, want %s", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want {
+				t.Errorf("got %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseIOLimits(t *testing.T) {
+	base := models.IOLimits{Rotational: 1, SSD: 4, NVMe: 16, Unknown: 2}
+	tests := []struct {
+		in      string
+		want    models.IOLimits
+		wantErr bool
+	}{
+		{"", base, false},
+		{"nvme=32", models.IOLimits{Rotational: 1, SSD: 4, NVMe: 32, Unknown: 2}, false},
+		{" rotational = 2 , ssd=8,", models.IOLimits{Rotational: 2, SSD: 8, NVMe: 16, Unknown: 2}, false},
+		{"unknown=1", models.IOLimits{Rotational: 1, SSD: 4, NVMe: 16, Unknown: 1}, false},
+		{"ssd=0", base, true},
+		{"ssd", base, true},
+		{"ssd=many", base, true},
+		{"floppy=1", base, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.in, func(t *testing.T) {
+			got, err := parseIOLimits(tt.in, base)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("err = %v, want error %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("got %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSortGroups(t *testing.T) {
+	f := func(path string, size int64) models.FileInfo {
+		return models.FileInfo{Path: path, Size: size}
+	}
+	groups := []models.DuplicateGroup{
+		{ID: "small", Kind: models.KindExact, Files: []models.FileInfo{f("/b/x", 10), f("/a/x", 10)}},
+		{ID: "similar", Kind: models.KindSimilar, Files: []models.FileInfo{f("/z/rep.jpg", 100), f("/a/copy.jpg", 50)}},
+		{ID: "large", Kind: models.KindExact, Files: []models.FileInfo{f("/c/y", 100), f("/c/x", 100)}},
+		{ID: "tie", Kind: models.KindExact, Files: []models.FileInfo{f("/0/z", 10), f("/0/y", 10)}},
+	}
+	for i := range groups {
+		groups[i].Recalculate()
+	}
+	sortGroups(groups)
+
+	var ids []string
+	for _, g := range groups {
+		ids = append(ids, g.ID)
+	}
+	if want := []string{"large", "similar", "tie", "small"}; !reflect.DeepEqual(ids, want) {
+		t.Errorf("order = %v, want %v", ids, want)
+	}
+	if groups[0].Files[0].Path != "/c/x" {
+		t.Errorf("exact group files not sorted by path: %v", groups[0].Files)
+	}
+	if groups[1].Files[0].Path != "/z/rep.jpg" {
+		t.Errorf("similar group lost its representative: %v", groups[1].Files)
+	}
+}
+
+func TestScanExitCodes(t *testing.T) {
+	empty := t.TempDir()
+	dups := t.TempDir()
+	for _, name := range []string{"a.txt", "b.txt"} {
+		if err := os.WriteFile(filepath.Join(dups, name), []byte("duplicate content"), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	cancelled, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	tests := []struct {
+		name string
+		ctx  context.Context
+		args []string
+		want int
+	}{
+		{"no command", context.Background(), nil, ExitUsage},
+		{"help", context.Background(), []string{"help"}, ExitOK},
+		{"unknown command", context.Background(), []string{"wipe"}, ExitUsage},
+		{"scan help", context.Background(), []string{"scan", "-h"}, ExitOK},
+		{"no path", context.Background(), []string{"scan"}, ExitUsage},
+		{"bad flag value", context.Background(), []string{"scan", "--max-rate", "5XB", empty}, ExitUsage},
+		{"bad format", context.Background(), []string{"scan", "--format", "xml", empty}, ExitUsage},
+		{"no duplicates", context.Background(), []string{"scan", "--no-cache", "--quiet", empty}, ExitOK},
+		{"duplicates", context.Background(), []string{"scan", "--no-cache", "--quiet", "--min-size", "0", dups}, ExitDuplicates},
+		{"unwritable output", context.Background(), []string{"scan", "--no-cache", "--quiet", "--output", filepath.Join(empty, "missing", "out.txt"), dups}, ExitError},
+		{"cancelled", cancelled, []string{"scan", "--no-cache", "--quiet", dups}, ExitCancelled},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var stdout, stderr bytes.Buffer
+			if got := Run(tt.ctx, tt.args, &stdout, &stderr); got != tt.want {
+				t.Errorf("exit code = %d, want %d; stderr:\n%s", got, tt.want, stderr.String())
+			}
+		})
+	}
+}
+
+func TestScanReportsOriginalInput(t *testing.T) {
+	var stdout, stderr bytes.Buffer
+	Run(context.Background(), []string{"scan", "--min-size", "3MBB", t.TempDir()}, &stdout, &stderr)
+	if !strings.Contains(stderr.String(), `"3MBB"`) {
+		t.Errorf("stderr = %q, want the value as given", stderr.String())
+	}
+}
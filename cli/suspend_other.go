@@ -0,0 +1,10 @@
+//go:build !unix
+
+package cli
+
+import "folder-cleaner-go/scanner"
+
+// watchSuspend does nothing: this platform has no SIGTSTP or SIGCONT.
+func watchSuspend(s *scanner.Scanner) (stop func()) {
+	return func() {}
+}
@@ -0,0 +1,40 @@
+//go:build unix
+
+package cli
+
+import (
+	"os"
+	"os/signal"
+	"syscall"
+
+	"folder-cleaner-go/scanner"
+)
+
+// watchSuspend pauses s when the process receives SIGTSTP (e.g. Ctrl-Z) and
+// resumes it on SIGCONT. After pausing, the process stops itself as SIGTSTP
+// normally would, so shell job control (fg, bg) keeps working. The returned
+// function stops watching.
+func watchSuspend(s *scanner.Scanner) (stop func()) {
+	sigs := make(chan os.Signal, 1)
+	signal.Notify(sigs, syscall.SIGTSTP, syscall.SIGCONT)
+	done := make(chan struct{})
+	go func() {
+		for {
+			select {
+			case <-done:
+				return
+			case sig := <-sigs:
+				if sig == syscall.SIGCONT {
+					s.Resume()
+					continue
+				}
+				s.Pause()
+				_ = syscall.Kill(os.Getpid(), syscall.SIGSTOP)
+			}
+		}
+	}()
+	return func() {
+		signal.Stop(sigs)
+		close(done)
+	}
+}
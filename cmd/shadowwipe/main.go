@@ -0,0 +1,13 @@
+// Command shadowwipe is the headless build of ShadowWipe. It has no UI or
+// cgo dependencies, so it can run on servers and from cron.
+package main
+
+import (
+	"os"
+
+	"folder-cleaner-go/cli"
+)
+
+func main() {
+	os.Exit(cli.Main(os.Args[1:]))
+}
@@ -0,0 +1,56 @@
+package export
+
+import (
+	"encoding/csv"
+	"io"
+	"strconv"
+	"time"
+)
+
+var csvHeader = []string{"group_id", "kind", "similarity", "max_distance", "hash", "path", "size", "modified", "hard_link_of", "read_only", "distance", "file_similarity", "transform", "skip_reason", "skip_stage", "skip_error"}
+
+// WriteCSV writes one row per file, with its group's ID and kind, followed by
+// a row of kind "skipped" per file the scan could not examine, giving the
+// reason, stage and error in the skip columns.
+func WriteCSV(w io.Writer, r Report) error {
+	cw := csv.NewWriter(w)
+	if err := cw.Write(csvHeader); err != nil {
+		return err
+	}
+
+	for _, g := range r.Groups {
+		for _, f := range g.Files {
+			row := []string{
+				g.ID,
+				string(g.Kind),
+				strconv.FormatFloat(g.Similarity, 'f', -1, 64),
+				strconv.Itoa(g.MaxDistance),
+				groupHash(g, f),
+				f.Path,
+				strconv.FormatInt(f.Size, 10),
+				time.Unix(f.Modified, 0).UTC().Format(time.RFC3339),
+				f.HardLinkOf,
+				strconv.FormatBool(f.ReadOnly),
+				strconv.Itoa(f.Distance),
+				strconv.FormatFloat(f.Similarity, 'f', -1, 64),
+				string(f.Transform),
+				"", "", "",
+			}
+			if err := cw.Write(row); err != nil {
+				return err
+			}
+		}
+	}
+
+	for _, f := range r.Skipped {
+		row := make([]string, len(csvHeader))
+		row[1], row[5] = "skipped", f.Path
+		copy(row[len(row)-3:], []string{string(f.Reason), f.Stage, f.Error})
+		if err := cw.Write(row); err != nil {
+			return err
+		}
+	}
+
+	cw.Flush()
+	return cw.Error()
+}
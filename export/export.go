@@ -0,0 +1,118 @@
+// Package export renders scan results as JSON, CSV or self-contained HTML
+// reports for review outside the app.
+package export
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"folder-cleaner-go/models"
+)
+
+// Format names an export format.
+type Format string
+
+const (
+	FormatJSON Format = "json"
+	FormatCSV  Format = "csv"
+	FormatHTML Format = "html"
+)
+
+// Formats lists the supported formats, e.g. for flag help.
+var Formats = []Format{FormatJSON, FormatCSV, FormatHTML}
+
+// Report is the data written by every format.
+type Report struct {
+	GeneratedAt time.Time               `json:"generated_at"`
+	Totals      Totals                  `json:"totals"`
+	Groups      []models.DuplicateGroup `json:"groups"`
+	Skipped     []models.SkippedFile    `json:"skipped"` // files the scan could not examine
+}
+
+// Totals summarises a report.
+type Totals struct {
+	Groups     int   `json:"groups"`
+	Files      int   `json:"files"`
+	TotalSize  int64 `json:"total_size"`
+	WastedSize int64 `json:"wasted_size"`
+	Skipped    int   `json:"skipped"`
+}
+
+// NewReport builds a report for groups and the files the scan skipped,
+// computing totals.
+func NewReport(groups []models.DuplicateGroup, skipped []models.SkippedFile) Report {
+	if groups == nil {
+		groups = []models.DuplicateGroup{}
+	}
+	if skipped == nil {
+		skipped = []models.SkippedFile{}
+	}
+	r := Report{GeneratedAt: time.Now(), Groups: groups, Skipped: skipped}
+	r.Totals.Groups = len(groups)
+	r.Totals.Skipped = len(skipped)
+	for _, g := range groups {
+		r.Totals.Files += len(g.Files)
+		r.Totals.TotalSize += g.TotalSize
+		r.Totals.WastedSize += g.WastedSize
+	}
+	return r
+}
+
+// ParseFormat validates a format name.
+func ParseFormat(s string) (Format, error) {
+	for _, f := range Formats {
+		if string(f) == s {
+			return f, nil
+		}
+	}
+	return "", fmt.Errorf("unknown export format %q (want json, csv or html)", s)
+}
+
+// Extension returns the file extension for f, including the dot.
+func (f Format) Extension() string {
+	return "." + string(f)
+}
+
+// Write renders r to w in the given format.
+func Write(w io.Writer, format Format, r Report) error {
+	switch format {
+	case FormatJSON:
+		return WriteJSON(w, r)
+	case FormatCSV:
+		return WriteCSV(w, r)
+	case FormatHTML:
+		return WriteHTML(w, r)
+	default:
+		return fmt.Errorf("unknown export format %q", format)
+	}
+}
+
+// WriteJSON writes the full report, including every FileInfo field.
+func WriteJSON(w io.Writer, r Report) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(r)
+}
+
+// groupHash returns the hash a group was matched on.
+func groupHash(g models.DuplicateGroup, f models.FileInfo) string {
+	if g.Kind == models.KindSimilar {
+		// The image's own hash, without any rotated or mirrored variants
+		h, _, _ := strings.Cut(f.PerceptualHash, ",")
+		return h
+	}
+	return f.FullHash
+}
+
+// groupAlgorithm returns the perceptual hash algorithm a similar group was
+// matched with, or "" for exact groups.
+func groupAlgorithm(g models.DuplicateGroup) string {
+	if g.Kind != models.KindSimilar || len(g.Files) == 0 {
+		return ""
+	}
+	algo, _, _ := strings.Cut(g.Files[0].PerceptualHash, ":")
+	return algo
+}
@@ -0,0 +1,122 @@
+package export
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"folder-cleaner-go/models"
+)
+
+// testReport has an exact group, a similar group and a skipped file.
+func testReport() Report {
+	modified := time.Date(2024, 3, 4, 5, 6, 7, 0, time.UTC).Unix()
+	groups := []models.DuplicateGroup{
+		{
+			ID: "g1", Kind: models.KindExact,
+			Files: []models.FileInfo{
+				{Path: "/a/x.bin", Size: 100, Modified: modified, Inode: 1, FullHash: "abc123"},
+				{Path: "/ref/x.bin", Size: 100, Modified: modified, Inode: 2, FullHash: "abc123", ReadOnly: true},
+			},
+		},
+		{
+			ID: "g2", Kind: models.KindSimilar, Similarity: 96.9, MaxDistance: 2,
+			Files: []models.FileInfo{
+				{Path: "/p/rep.jpg", Size: 300, Modified: modified, Inode: 3, PerceptualHash: "perception:00000000000000ff,1,2,3,4", Similarity: 100},
+				{Path: "/p/turned.jpg", Size: 200, Modified: modified, Inode: 4, PerceptualHash: "perception:00000000000000fc", Distance: 2, Similarity: 96.9, Transform: models.TransformRotate90},
+			},
+		},
+	}
+	for i := range groups {
+		groups[i].Recalculate()
+	}
+	skipped := []models.SkippedFile{
+		{Path: "/a/locked.bin", Reason: models.SkipPermission, Stage: "full-hashing", Error: "open /a/locked.bin: permission denied"},
+	}
+	return NewReport(groups, skipped)
+}
+
+func TestWriteCSV(t *testing.T) {
+	var buf bytes.Buffer
+	if err := WriteCSV(&buf, testReport()); err != nil {
+		t.Fatal(err)
+	}
+	rows, err := csv.NewReader(&buf).ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := [][]string{
+		csvHeader,
+		{"g1", "exact", "0", "0", "abc123", "/a/x.bin", "100", "2024-03-04T05:06:07Z", "", "false", "0", "0", "", "", "", ""},
+		{"g1", "exact", "0", "0", "abc123", "/ref/x.bin", "100", "2024-03-04T05:06:07Z", "", "true", "0", "0", "", "", "", ""},
+		{"g2", "similar", "96.9", "2", "perception:00000000000000ff", "/p/rep.jpg", "300", "2024-03-04T05:06:07Z", "", "false", "0", "100", "", "", "", ""},
+		{"g2", "similar", "96.9", "2", "perception:00000000000000fc", "/p/turned.jpg", "200", "2024-03-04T05:06:07Z", "", "false", "2", "96.9", "rotate90", "", "", ""},
+		{"", "skipped", "", "", "", "/a/locked.bin", "", "", "", "", "", "", "", "permission_denied", "full-hashing", "open /a/locked.bin: permission denied"},
+	}
+	if !reflect.DeepEqual(rows, want) {
+		t.Errorf("rows =\n%q\nwant\n%q", rows, want)
+	}
+	for i, row := range rows {
+		if len(row) != len(csvHeader) {
+			t.Errorf("row %d has %d columns, want %d", i, len(row), len(csvHeader))
+		}
+	}
+}
+
+func TestWriteJSON(t *testing.T) {
+	var buf bytes.Buffer
+	if err := WriteJSON(&buf, testReport()); err != nil {
+		t.Fatal(err)
+	}
+	var got Report
+	if err := json.Unmarshal(buf.Bytes(), &got); err != nil {
+		t.Fatal(err)
+	}
+	want := Totals{Groups: 2, Files: 4, TotalSize: 700, WastedSize: 300, Skipped: 1}
+	if got.Totals != want {
+		t.Errorf("totals = %+v, want %+v", got.Totals, want)
+	}
+	if len(got.Groups) != 2 || len(got.Skipped) != 1 || got.Skipped[0].Stage != "full-hashing" {
+		t.Errorf("groups %d, skipped %+v", len(got.Groups), got.Skipped)
+	}
+}
+
+func TestWriteHTML(t *testing.T) {
+	var buf bytes.Buffer
+	if err := WriteHTML(&buf, testReport()); err != nil {
+		t.Fatal(err)
+	}
+	out := buf.String()
+	for _, want := range []string{
+		"similar perception 96.9%, max distance 2",
+		`<code title="perception:00000000000000ff">00000000000000ff</code>`,
+		"/a/locked.bin",
+		"permission denied",
+		"full-hashing",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("report is missing %q", want)
+		}
+	}
+}
+
+func TestParseFormat(t *testing.T) {
+	for _, f := range Formats {
+		if got, err := ParseFormat(string(f)); err != nil || got != f {
+			t.Errorf("ParseFormat(%q) = %q, %v", f, got, err)
+		}
+	}
+	for _, s := range []string{"", "text", "xml", "JSON"} {
+		if _, err := ParseFormat(s); err == nil {
+			t.Errorf("ParseFormat(%q) accepted an unknown format", s)
+		}
+	}
+	if err := Write(&bytes.Buffer{}, Format("xml"), testReport()); err == nil {
+		t.Error("Write accepted an unknown format")
+	}
+}
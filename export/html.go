@@ -0,0 +1,114 @@
+package export
+
+import (
+	"fmt"
+	"html/template"
+	"io"
+	"strings"
+	"time"
+)
+
+var htmlTemplate = template.Must(template.New("report").Funcs(template.FuncMap{
+	"size":      FormatSize,
+	"time":      func(unix int64) string { return time.Unix(unix, 0).Format("2006-01-02 15:04") },
+	"hash":      groupHash,
+	"algorithm": groupAlgorithm,
+	"inc":       func(i int) int { return i + 1 },
+	"short": func(s string) string {
+		// Perceptual hashes are tagged with their algorithm, shown once
+		// per group instead
+		if _, hex, ok := strings.Cut(s, ":"); ok {
+			s = hex
+		}
+		if len(s) > 16 {
+			return s[:16] + "…"
+		}
+		return s
+	},
+}).Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<title>ShadowWipe report</title>
+<style>
+body { font-family: -apple-system, "Segoe UI", Roboto, sans-serif; margin: 2rem; color: #1b2636; }
+h1 { margin-bottom: 0.25rem; }
+.meta { color: #667; margin-top: 0; }
+.totals { display: flex; gap: 2rem; margin: 1.5rem 0; }
+.totals div { background: #f2f4f8; border-radius: 6px; padding: 0.75rem 1.25rem; }
+.totals b { display: block; font-size: 1.4rem; }
+h2 { font-size: 1rem; margin: 2rem 0 0.5rem; }
+.kind { font-size: 0.75rem; text-transform: uppercase; background: #dde3ee; border-radius: 4px; padding: 0.1rem 0.4rem; }
+table { border-collapse: collapse; width: 100%; font-size: 0.9rem; }
+th, td { text-align: left; padding: 0.35rem 0.6rem; border-bottom: 1px solid #e3e6ec; }
+th { background: #f7f8fa; }
+td.num { text-align: right; white-space: nowrap; }
+td.path { word-break: break-all; }
+code { font-size: 0.8rem; color: #556; }
+.link { color: #889; font-style: italic; }
+</style>
+</head>
+<body>
+<h1>ShadowWipe duplicate report</h1>
+<p class="meta">Generated {{.GeneratedAt.Format "2006-01-02 15:04:05 MST"}}</p>
+<div class="totals">
+<div><b>{{.Totals.Groups}}</b>groups</div>
+<div><b>{{.Totals.Files}}</b>files</div>
+<div><b>{{size .Totals.TotalSize}}</b>total size</div>
+<div><b>{{size .Totals.WastedSize}}</b>reclaimable</div>
+{{if .Totals.Skipped}}<div><b>{{.Totals.Skipped}}</b>skipped</div>{{end}}
+</div>
+{{range $i, $g := .Groups}}
+<h2>Group {{inc $i}} <span class="kind">{{$g.Kind}}{{if eq $g.Kind "similar"}} {{with algorithm $g}}{{.}} {{end}}{{printf "%.1f" $g.Similarity}}%, max distance {{$g.MaxDistance}}{{end}}</span>
+ — {{len $g.Files}} files, {{size $g.WastedSize}} reclaimable</h2>
+<table>
+<tr><th>Path</th><th>Size</th><th>Modified</th><th>Hash</th></tr>
+{{range $g.Files}}<tr>
+<td class="path">{{.Path}}{{if .HardLinkOf}} <span class="link">(hard link of {{.HardLinkOf}})</span>{{end}}{{if .ReadOnly}} <span class="link">(reference)</span>{{end}}{{if eq $g.Kind "similar"}} <span class="link">({{printf "%.1f" .Similarity}}%, distance {{.Distance}}{{with .Transform.Label}}, {{.}}{{end}})</span>{{end}}</td>
+<td class="num">{{size .Size}}</td>
+<td class="num">{{time .Modified}}</td>
+<td><code title="{{hash $g .}}">{{short (hash $g .)}}</code></td>
+</tr>{{end}}
+</table>
+{{end}}
+{{with .Skipped}}
+<h2>Skipped — {{len .}} files could not be examined</h2>
+<table>
+<tr><th>Path</th><th>Reason</th><th>Stage</th><th>Error</th></tr>
+{{range .}}<tr>
+<td class="path">{{.Path}}</td>
+<td>{{.Reason.Label}}</td>
+<td>{{.Stage}}</td>
+<td><code>{{.Error}}</code></td>
+</tr>{{end}}
+</table>
+{{end}}
+</body>
+</html>
+`))
+
+// WriteHTML writes a self-contained HTML page with totals, a table per group
+// and one of skipped files. It has no external assets, so it can be mailed
+// or archived as is.
+func WriteHTML(w io.Writer, r Report) error {
+	return htmlTemplate.Execute(w, r)
+}
+
+// FormatSize renders bytes using the same units as the frontend.
+func FormatSize(bytes int64) string {
+	const unit = 1024
+	if bytes < unit {
+		return fmt.Sprintf("%d B", bytes)
+	}
+	units := []string{"KB", "MB", "GB", "TB", "PB"}
+	size := float64(bytes) / unit
+	i := 0
+	for size >= unit && i < len(units)-1 {
+		size /= unit
+		i++
+	}
+	if size < 10 {
+		return fmt.Sprintf("%.1f %s", size, units[i])
+	}
+	return fmt.Sprintf("%.0f %s", size, units[i])
+}
@@ -1,11 +1,12 @@
 import { useState, useEffect, useCallback } from 'react';
 import { DirectoryPicker } from './components/DirectoryPicker';
-import { SettingsPanel } from './components/SettingsPanel';
+import { SettingsPanel, maxThreshold } from './components/SettingsPanel';
 import { ScanProgress } from './components/ScanProgress';
 import { DuplicateList } from './components/DuplicateList';
 import { useScan } from './hooks/useScan';
-import { GetSettings, SaveSettings } from '../wailsjs/go/main/App';
+import { GetScanCheckpoint, GetSettings, SaveSettings, SetReadRateLimit } from '../wailsjs/go/main/App';
 import { models } from '../wailsjs/go/models';
+import { STAGE_LABELS } from './types';
 import './App.css';
 
 function App() {
@@ -14,11 +15,23 @@ function App() {
     const [minFileSizeUnit, setMinFileSizeUnit] = useState('KB');
     const [similarityThreshold, setSimilarityThreshold] = useState(0);
     const [skipHidden, setSkipHidden] = useState(true);
+    const [clusterMode, setClusterMode] = useState('greedy');
+    const [hashAlgorithm, setHashAlgorithm] = useState('perception');
+    const [matchTransforms, setMatchTransforms] = useState(false);
+    const [ioLimits, setIOLimits] = useState<models.IOLimits>(new models.IOLimits());
+    const [maxReadRate, setMaxReadRate] = useState(0);
+    const [idleIOPriority, setIdleIOPriority] = useState(false);
     const [excludedDirs, setExcludedDirs] = useState<string[]>([]);
+    const [referencePaths, setReferencePaths] = useState<string[]>([]);
     const [settingsLoaded, setSettingsLoaded] = useState(false);
     const [settingsOpen, setSettingsOpen] = useState(false);
+    // A saved, unfinished scan with the same settings, offered before starting
+    const [resumeOffer, setResumeOffer] = useState<{
+        settings: models.ScanSettings;
+        checkpoint: models.ScanCheckpoint;
+    } | null>(null);
 
-    const { status, progress, duplicateCount, error, startScan, cancelScan, reset } = useScan();
+    const { status, progress, duplicateCount, error, startScan, cancelScan, pauseScan, resumeScan, reset } = useScan();
 
     const scanning = status === 'scanning';
 
@@ -30,7 +43,14 @@ function App() {
             setMinFileSizeUnit(s.min_file_size_unit || 'KB');
             setSimilarityThreshold(s.similarity_threshold);
             setSkipHidden(s.skip_hidden);
+            setClusterMode(s.cluster_mode || 'greedy');
+            setHashAlgorithm(s.hash_algorithm || 'perception');
+            setMatchTransforms(!!s.match_transforms);
+            setIOLimits(s.io_limits || new models.IOLimits());
+            setMaxReadRate(s.max_read_rate || 0);
+            setIdleIOPriority(!!s.idle_io_priority);
             setExcludedDirs(s.excluded_dirs || []);
+            setReferencePaths(s.reference_paths || []);
             setSettingsLoaded(true);
         });
     }, []);
@@ -42,7 +62,14 @@ function App() {
         minFileSizeUnit: string;
         similarityThreshold: number;
         skipHidden: boolean;
+        clusterMode: string;
+        hashAlgorithm: string;
+        matchTransforms: boolean;
+        ioLimits: models.IOLimits;
+        maxReadRate: number;
+        idleIOPriority: boolean;
         excludedDirs: string[];
+        referencePaths: string[];
     }>) => {
         if (!settingsLoaded) return;
         const s = new models.ScanSettings({
@@ -51,10 +78,17 @@ function App() {
             min_file_size_unit: overrides?.minFileSizeUnit ?? minFileSizeUnit,
             similarity_threshold: overrides?.similarityThreshold ?? similarityThreshold,
             skip_hidden: overrides?.skipHidden ?? skipHidden,
+            cluster_mode: overrides?.clusterMode ?? clusterMode,
+            hash_algorithm: overrides?.hashAlgorithm ?? hashAlgorithm,
+            match_transforms: overrides?.matchTransforms ?? matchTransforms,
+            io_limits: overrides?.ioLimits ?? ioLimits,
+            max_read_rate: overrides?.maxReadRate ?? maxReadRate,
+            idle_io_priority: overrides?.idleIOPriority ?? idleIOPriority,
             excluded_dirs: overrides?.excludedDirs ?? excludedDirs,
+            reference_paths: overrides?.referencePaths ?? referencePaths,
         });
         SaveSettings(s);
-    }, [settingsLoaded, paths, minFileSize, minFileSizeUnit, similarityThreshold, skipHidden, excludedDirs]);
+    }, [settingsLoaded, paths, minFileSize, minFileSizeUnit, similarityThreshold, skipHidden, clusterMode, hashAlgorithm, matchTransforms, ioLimits, maxReadRate, idleIOPriority, excludedDirs, referencePaths]);
 
     const handlePathsChange = (newPaths: string[]) => {
         setPaths(newPaths);
@@ -81,11 +115,51 @@ function App() {
         persistSettings({ skipHidden: value });
     };
 
+    const handleClusterModeChange = (mode: string) => {
+        setClusterMode(mode);
+        persistSettings({ clusterMode: mode });
+    };
+
+    const handleHashAlgorithmChange = (algorithm: string) => {
+        // Thresholds are in bits, so clamp when moving to a shorter hash
+        const threshold = Math.min(similarityThreshold, maxThreshold(algorithm));
+        setHashAlgorithm(algorithm);
+        setSimilarityThreshold(threshold);
+        persistSettings({ hashAlgorithm: algorithm, similarityThreshold: threshold });
+    };
+
+    const handleMatchTransformsChange = (value: boolean) => {
+        setMatchTransforms(value);
+        persistSettings({ matchTransforms: value });
+    };
+
+    const handleIOLimitsChange = (limits: models.IOLimits) => {
+        setIOLimits(limits);
+        persistSettings({ ioLimits: limits });
+    };
+
+    const handleMaxReadRateChange = (bytesPerSecond: number) => {
+        setMaxReadRate(bytesPerSecond);
+        persistSettings({ maxReadRate: bytesPerSecond });
+        // Applies to a scan already running as well
+        SetReadRateLimit(bytesPerSecond);
+    };
+
+    const handleIdleIOPriorityChange = (value: boolean) => {
+        setIdleIOPriority(value);
+        persistSettings({ idleIOPriority: value });
+    };
+
     const handleExcludedDirsChange = (dirs: string[]) => {
         setExcludedDirs(dirs);
         persistSettings({ excludedDirs: dirs });
     };
 
+    const handleReferencePathsChange = (dirs: string[]) => {
+        setReferencePaths(dirs);
+        persistSettings({ referencePaths: dirs });
+    };
+
     const handleStartScan = () => {
         if (paths.length > 0) {
             const settings = new models.ScanSettings({
@@ -94,12 +168,32 @@ function App() {
                 min_file_size_unit: minFileSizeUnit,
                 similarity_threshold: similarityThreshold,
                 skip_hidden: skipHidden,
+                cluster_mode: clusterMode,
+                hash_algorithm: hashAlgorithm,
+                match_transforms: matchTransforms,
+                io_limits: ioLimits,
+                max_read_rate: maxReadRate,
+                idle_io_priority: idleIOPriority,
                 excluded_dirs: excludedDirs,
+                reference_paths: referencePaths,
+            });
+            GetScanCheckpoint(settings).then((checkpoint) => {
+                if (checkpoint) {
+                    setResumeOffer({ settings, checkpoint });
+                } else {
+                    startScan(settings);
+                }
             });
-            startScan(settings);
         }
     };
 
+    const handleResumeOffer = (resume: boolean) => {
+        if (resumeOffer) {
+            startScan(resumeOffer.settings, resume);
+        }
+        setResumeOffer(null);
+    };
+
     return (
         <div id="App">
             <div className="app-header">
@@ -122,12 +216,26 @@ function App() {
                 minFileSizeUnit={minFileSizeUnit}
                 similarityThreshold={similarityThreshold}
                 skipHidden={skipHidden}
+                clusterMode={clusterMode}
+                matchTransforms={matchTransforms}
+                ioLimits={ioLimits}
+                maxReadRate={maxReadRate}
+                idleIOPriority={idleIOPriority}
+                hashAlgorithm={hashAlgorithm}
                 excludedDirs={excludedDirs}
+                referencePaths={referencePaths}
                 onMinFileSizeChange={handleMinFileSizeChange}
                 onMinFileSizeUnitChange={handleMinFileSizeUnitChange}
                 onSimilarityThresholdChange={handleSimilarityThresholdChange}
                 onSkipHiddenChange={handleSkipHiddenChange}
+                onMatchTransformsChange={handleMatchTransformsChange}
+                onIOLimitsChange={handleIOLimitsChange}
+                onMaxReadRateChange={handleMaxReadRateChange}
+                onIdleIOPriorityChange={handleIdleIOPriorityChange}
+                onClusterModeChange={handleClusterModeChange}
+                onHashAlgorithmChange={handleHashAlgorithmChange}
                 onExcludedDirsChange={handleExcludedDirsChange}
+                onReferencePathsChange={handleReferencePathsChange}
             />
 
             {status === 'idle' && (
@@ -146,12 +254,37 @@ function App() {
                 duplicateCount={duplicateCount}
                 error={error}
                 onCancel={cancelScan}
+                onPause={pauseScan}
+                onResume={resumeScan}
                 onReset={reset}
             />
 
             {status === 'complete' && duplicateCount > 0 && (
                 <DuplicateList onReset={reset} />
             )}
+
+            {resumeOffer && (
+                <div className="confirm-overlay" onClick={() => setResumeOffer(null)}>
+                    <div className="confirm-dialog" onClick={(e) => e.stopPropagation()}>
+                        <p>Resume the interrupted scan of these folders?</p>
+                        <p className="confirm-detail">
+                            {resumeOffer.checkpoint.files_hashed.toLocaleString()} files were hashed; it stopped while{' '}
+                            {(STAGE_LABELS[resumeOffer.checkpoint.stage] || resumeOffer.checkpoint.stage).toLowerCase()}
+                            {resumeOffer.checkpoint.saved > 0 &&
+                                ` (saved ${new Date(resumeOffer.checkpoint.saved * 1000).toLocaleString()})`}
+                            . Files added since will not be picked up.
+                        </p>
+                        <div className="confirm-actions">
+                            <button className="btn btn-secondary" onClick={() => handleResumeOffer(false)}>
+                                Start Over
+                            </button>
+                            <button className="btn btn-primary" onClick={() => handleResumeOffer(true)}>
+                                Resume
+                            </button>
+                        </div>
+                    </div>
+                </div>
+            )}
         </div>
     );
 }
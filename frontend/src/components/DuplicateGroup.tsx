@@ -6,11 +6,32 @@ interface FileInfo {
     size: number;
     name: string;
     modified: number;
+    distance?: number;
+    similarity?: number;
+    transform?: string;
+    read_only?: boolean;
+    hard_link_of?: string;
 }
 
+// isLocked reports whether a file is always kept: reference copies cannot be
+// removed, and removing a hard link of another file frees no space.
+export function isLocked(file: FileInfo): boolean {
+    return !!file.read_only || !!file.hard_link_of;
+}
+
+// How a similar file is rotated or mirrored relative to the representative
+const transformLabels: Record<string, string> = {
+    rotate90: 'rotated 90°',
+    rotate180: 'rotated 180°',
+    rotate270: 'rotated 270°',
+    flip: 'mirrored',
+};
+
 export interface DuplicateGroupData {
     id: string;
     kind: string;
+    similarity?: number;
+    max_distance?: number;
     files: FileInfo[];
     total_size: number;
     wasted_size: number;
@@ -28,12 +49,15 @@ export function DuplicateGroup({ group, keptPaths, onToggle, onKeepFirst, onKeep
     return (
         <div className="group-card">
             <div className="group-header">
-                <span>{group.files.length} files</span>
+                <span>
+                    {group.files.length} files
+                    {group.kind === 'similar' && ` · ${group.similarity?.toFixed(1)}% similar`}
+                </span>
                 <span className="group-header-actions">
                     <button
                         className="btn-action"
                         onClick={() => onKeepFirst(group.id)}
-                        title="Keep first file only"
+                        title="Keep first file only, plus reference copies and hard links"
                     >
                         Keep First
                     </button>
@@ -48,24 +72,39 @@ export function DuplicateGroup({ group, keptPaths, onToggle, onKeepFirst, onKeep
                 </span>
             </div>
 
-            {group.files.map((file) => {
-                const isKept = keptPaths.has(file.path);
+            {group.files.map((file, index) => {
+                const locked = isLocked(file);
+                const isKept = locked || keptPaths.has(file.path);
 
                 return (
                     <label
                         key={file.path}
-                        className={`file-row${isKept ? ' selected' : ' will-trash'}`}
+                        className={`file-row${isKept ? ' selected' : ' will-trash'}${locked ? ' locked' : ''}`}
                     >
                         <input
                             type="checkbox"
                             checked={isKept}
+                            disabled={locked}
                             onChange={() => onToggle(group.id, file.path)}
                         />
                         <div className="file-details">
-                            <div className="file-name">{file.name}</div>
+                            <div className="file-name">
+                                {file.name}
+                                {file.read_only && <span className="file-badge">reference</span>}
+                                {file.hard_link_of && (
+                                    <span className="file-badge" title={file.hard_link_of}>hard link</span>
+                                )}
+                            </div>
                             <div className="file-path">{file.path}</div>
                             <div className="file-meta">
                                 <span className="file-date">{formatDate(file.modified)}</span>
+                                {group.kind === 'similar' && (
+                                    <span className="file-date">
+                                        {index === 0
+                                            ? 'representative'
+                                            : `${file.similarity?.toFixed(1)}% (distance ${file.distance ?? 0}${file.transform ? `, ${transformLabels[file.transform]}` : ''})`}
+                                    </span>
+                                )}
                                 <span className="file-actions">
                                     <button
                                         className="btn-action"
@@ -1,9 +1,10 @@
 import { useState, useEffect, useMemo } from 'react';
-import { DuplicateGroup, DuplicateGroupData } from './DuplicateGroup';
+import { DuplicateGroup, DuplicateGroupData, isLocked } from './DuplicateGroup';
 import { formatSize } from '../utils/format';
-import { GetDuplicateGroups, DeleteFiles } from '../../wailsjs/go/main/App';
+import { SkippedFile, SKIP_REASON_LABELS } from '../types';
+import { GetDuplicateGroups, GetScanStats, GetSkippedFiles, DeleteFiles } from '../../wailsjs/go/main/App';
 
-type SortBy = 'wasted-desc' | 'wasted-asc' | 'files-desc' | 'name-asc' | 'name-desc';
+type SortBy = 'wasted-desc' | 'wasted-asc' | 'files-desc' | 'similarity-desc' | 'name-asc' | 'name-desc';
 type FilterType = 'all' | 'images' | 'documents' | 'audio' | 'video' | 'archives' | 'code' | 'other';
 
 const FILE_TYPE_MAP: Record<string, FilterType> = {
@@ -29,6 +30,10 @@ function getGroupType(group: DuplicateGroupData): FilterType {
     return FILE_TYPE_MAP[ext] || 'other';
 }
 
+function groupSimilarity(group: DuplicateGroupData): number {
+    return group.kind === 'similar' ? group.similarity ?? 0 : 100;
+}
+
 const FILTER_LABELS: Record<FilterType, string> = {
     all: 'All',
     images: 'Images',
@@ -58,6 +63,9 @@ export function DuplicateList({ onReset }: Props) {
     const [showConfirm, setShowConfirm] = useState(false);
     const [sortBy, setSortBy] = useState<SortBy>('wasted-desc');
     const [filterType, setFilterType] = useState<FilterType>('all');
+    const [undecodable, setUndecodable] = useState(0);
+    const [skipped, setSkipped] = useState<SkippedFile[]>([]);
+    const [showSkipped, setShowSkipped] = useState(false);
 
     useEffect(() => {
         GetDuplicateGroups()
@@ -77,9 +85,17 @@ export function DuplicateList({ onReset }: Props) {
             .finally(() => {
                 setLoading(false);
             });
+        GetScanStats()
+            .then((stats) => setUndecodable(stats.undecodable_images))
+            .catch(() => setUndecodable(0));
+        GetSkippedFiles()
+            .then((files) => setSkipped(files || []))
+            .catch(() => setSkipped([]));
     }, []);
 
     const handleToggle = (groupId: string, path: string) => {
+        const file = groups.find((g) => g.id === groupId)?.files.find((f) => f.path === path);
+        if (file && isLocked(file)) return;
         setSelections((prev) => {
             const next = new Map(prev);
             const paths = new Set(prev.get(groupId) || []);
@@ -98,7 +114,10 @@ export function DuplicateList({ onReset }: Props) {
             const next = new Map(prev);
             const group = groups.find((g) => g.id === groupId);
             if (group && group.files.length > 0) {
-                next.set(groupId, new Set([group.files[0].path]));
+                // Reference copies and hard links are always kept
+                next.set(groupId, new Set(
+                    group.files.filter((f, i) => i === 0 || isLocked(f)).map((f) => f.path),
+                ));
             }
             return next;
         });
@@ -120,7 +139,7 @@ export function DuplicateList({ onReset }: Props) {
     groups.forEach((group) => {
         const kept = selections.get(group.id);
         if (kept) {
-            trashCount += group.files.length - kept.size;
+            trashCount += group.files.filter((f) => !kept.has(f.path) && !isLocked(f)).length;
         }
     });
 
@@ -148,6 +167,10 @@ export function DuplicateList({ onReset }: Props) {
             case 'files-desc':
                 sorted.sort((a, b) => b.files.length - a.files.length);
                 break;
+            case 'similarity-desc':
+                // Exact groups are identical, so they count as 100%
+                sorted.sort((a, b) => groupSimilarity(b) - groupSimilarity(a));
+                break;
             case 'name-asc':
                 sorted.sort((a, b) => (a.files[0]?.name || '').localeCompare(b.files[0]?.name || ''));
                 break;
@@ -164,7 +187,7 @@ export function DuplicateList({ onReset }: Props) {
             const kept = selections.get(group.id);
             if (kept) {
                 group.files.forEach((file) => {
-                    if (!kept.has(file.path)) {
+                    if (!kept.has(file.path) && !isLocked(file)) {
                         paths.push(file.path);
                     }
                 });
@@ -249,8 +272,35 @@ export function DuplicateList({ onReset }: Props) {
             <div className="duplicate-summary">
                 <strong>{groups.length}</strong> duplicate group{groups.length !== 1 ? 's' : ''} —{' '}
                 <strong>{formatSize(totalWasted)}</strong> wasted
+                {undecodable > 0 && (
+                    <> — {undecodable} image{undecodable !== 1 ? 's' : ''} could not be decoded</>
+                )}
+                {skipped.length > 0 && (
+                    <>
+                        {' — '}
+                        <button className="link-toggle" onClick={() => setShowSkipped((v) => !v)}>
+                            {skipped.length} file{skipped.length !== 1 ? 's' : ''} skipped
+                        </button>
+                    </>
+                )}
             </div>
 
+            {showSkipped && skipped.length > 0 && (
+                <div className="skipped-files">
+                    <p>These files could not be examined and are missing from the results:</p>
+                    <ul>
+                        {skipped.map((f) => (
+                            <li key={f.path}>
+                                <span className="fail-path">{f.path}</span>
+                                <span className="fail-reason" title={f.error}>
+                                    {SKIP_REASON_LABELS[f.reason] || f.reason}
+                                </span>
+                            </li>
+                        ))}
+                    </ul>
+                </div>
+            )}
+
             <div className="duplicate-info">
                 Checked files will be <strong>kept</strong>. Unchecked files will be moved to trash.
                 All files are checked by default — uncheck the duplicates you want to remove.
@@ -269,6 +319,7 @@ export function DuplicateList({ onReset }: Props) {
                         <option value="wasted-desc">Biggest first</option>
                         <option value="wasted-asc">Smallest first</option>
                         <option value="files-desc">Most files first</option>
+                        <option value="similarity-desc">Most alike first</option>
                         <option value="name-asc">Name A-Z</option>
                         <option value="name-desc">Name Z-A</option>
                     </select>
@@ -1,4 +1,5 @@
 import { ScanProgress as ScanProgressType, ScanStatus, STAGE_LABELS } from '../types';
+import { formatDuration, formatSize } from '../utils/format';
 
 interface Props {
     status: ScanStatus;
@@ -6,21 +7,31 @@ interface Props {
     duplicateCount: number;
     error: string;
     onCancel: () => void;
+    onPause: () => void;
+    onResume: () => void;
     onReset: () => void;
 }
 
-export function ScanProgress({ status, progress, duplicateCount, error, onCancel, onReset }: Props) {
+export function ScanProgress({ status, progress, duplicateCount, error, onCancel, onPause, onResume, onReset }: Props) {
     if (status === 'idle') return null;
 
     const stageLabel = STAGE_LABELS[progress.stage] || progress.stage;
-    const pct = progress.total > 0 ? Math.round((progress.processed / progress.total) * 100) : 0;
+    const bytesTotal = progress.bytes_total ?? 0;
+    const bytesDone = progress.bytes_done ?? 0;
+    // Hashing stages report bytes, which track large files better than counts
+    const pct = bytesTotal > 0
+        ? Math.round((bytesDone / bytesTotal) * 100)
+        : progress.total > 0 ? Math.round((progress.processed / progress.total) * 100) : 0;
 
     return (
         <div className="scan-progress">
             {status === 'scanning' && (
                 <>
                     <div className="progress-info">
-                        <span className="stage-label">{stageLabel}</span>
+                        <span className="stage-label">
+                            {stageLabel}
+                            {progress.paused && ' (paused)'}
+                        </span>
                         {progress.total > 0 && (
                             <span className="progress-count">
                                 {progress.processed.toLocaleString()} / {progress.total.toLocaleString()}
@@ -28,8 +39,30 @@ export function ScanProgress({ status, progress, duplicateCount, error, onCancel
                         )}
                     </div>
                     <div className="progress-bar-track">
-                        <div className="progress-bar-fill" style={{ width: `${pct}%` }} />
+                        <div
+                            className={`progress-bar-fill${progress.paused ? ' paused' : ''}`}
+                            style={{ width: `${pct}%` }}
+                        />
                     </div>
+                    {bytesTotal > 0 && (
+                        <div className="progress-details">
+                            <span>
+                                {formatSize(bytesDone)} of {formatSize(bytesTotal)}
+                                {!progress.paused && (progress.throughput ?? 0) > 0 && ` at ${formatSize(Math.round(progress.throughput!))}/s`}
+                            </span>
+                            {!progress.paused && (progress.eta ?? 0) > 0 && (
+                                <span>about {formatDuration(progress.eta!)} left</span>
+                            )}
+                        </div>
+                    )}
+                    {progress.current_file && (
+                        <div className="progress-file" title={progress.current_file}>
+                            {progress.current_file}
+                        </div>
+                    )}
+                    <button className="btn btn-secondary btn-pause" onClick={progress.paused ? onResume : onPause}>
+                        {progress.paused ? 'Resume' : 'Pause'}
+                    </button>
                     <button className="btn btn-cancel" onClick={onCancel}>
                         Cancel
                     </button>
@@ -1,6 +1,7 @@
 import { useState, useEffect } from 'react';
 import { BrowserOpenURL } from '../../wailsjs/runtime/runtime';
-import { GetBuildInfo } from '../../wailsjs/go/main/App';
+import { GetBuildInfo, SelectDirectory } from '../../wailsjs/go/main/App';
+import { models } from '../../wailsjs/go/models';
 
 interface Props {
     open: boolean;
@@ -9,12 +10,42 @@ interface Props {
     minFileSizeUnit: string;
     similarityThreshold: number;
     skipHidden: boolean;
+    clusterMode: string;
+    matchTransforms: boolean;
+    ioLimits: models.IOLimits;
+    maxReadRate: number;
+    idleIOPriority: boolean;
+    hashAlgorithm: string;
     excludedDirs: string[];
+    referencePaths: string[];
     onMinFileSizeChange: (value: number) => void;
     onMinFileSizeUnitChange: (unit: string) => void;
     onSimilarityThresholdChange: (value: number) => void;
     onSkipHiddenChange: (value: boolean) => void;
+    onMatchTransformsChange: (value: boolean) => void;
+    onIOLimitsChange: (limits: models.IOLimits) => void;
+    onMaxReadRateChange: (bytesPerSecond: number) => void;
+    onIdleIOPriorityChange: (value: boolean) => void;
+    onClusterModeChange: (mode: string) => void;
+    onHashAlgorithmChange: (algorithm: string) => void;
     onExcludedDirsChange: (dirs: string[]) => void;
+    onReferencePathsChange: (dirs: string[]) => void;
+}
+
+// Concurrent reads per device while hashing, by storage type
+const ioLimitRows: { key: keyof models.IOLimits; label: string }[] = [
+    { key: 'rotational', label: 'Spinning disks' },
+    { key: 'ssd', label: 'SATA SSDs' },
+    { key: 'nvme', label: 'NVMe drives' },
+    { key: 'unknown', label: 'Network / other' },
+];
+
+const MB = 1024 * 1024;
+
+// maxThreshold is the largest useful similarity threshold, in bits, for a
+// perceptual hash algorithm.
+export function maxThreshold(hashAlgorithm: string): number {
+    return hashAlgorithm === 'extended' ? 80 : 20;
 }
 
 export function SettingsPanel({
@@ -24,14 +55,28 @@ export function SettingsPanel({
     minFileSizeUnit,
     similarityThreshold,
     skipHidden,
+    clusterMode,
+    matchTransforms,
+    ioLimits,
+    maxReadRate,
+    idleIOPriority,
+    hashAlgorithm,
     excludedDirs,
+    referencePaths,
     onMinFileSizeChange,
     onMinFileSizeUnitChange,
     onSimilarityThresholdChange,
     onSkipHiddenChange,
+    onMatchTransformsChange,
+    onIOLimitsChange,
+    onMaxReadRateChange,
+    onIdleIOPriorityChange,
+    onClusterModeChange,
+    onHashAlgorithmChange,
     onExcludedDirsChange,
+    onReferencePathsChange,
 }: Props) {
-    const [activeTab, setActiveTab] = useState<'general' | 'exclusions' | 'about'>('general');
+    const [activeTab, setActiveTab] = useState<'general' | 'exclusions' | 'performance' | 'about'>('general');
     const [newExclusion, setNewExclusion] = useState('');
     const [buildInfo, setBuildInfo] = useState({ version: '', build_time: '', build_os: '', build_arch: '' });
 
@@ -51,6 +96,17 @@ export function SettingsPanel({
         onExcludedDirsChange(excludedDirs.filter((_, i) => i !== index));
     };
 
+    const addReferencePath = async () => {
+        const selected = await SelectDirectory();
+        if (selected && !referencePaths.includes(selected)) {
+            onReferencePathsChange([...referencePaths, selected]);
+        }
+    };
+
+    const removeReferencePath = (index: number) => {
+        onReferencePathsChange(referencePaths.filter((_, i) => i !== index));
+    };
+
     const handleExclusionKeyDown = (e: React.KeyboardEvent) => {
         if (e.key === 'Enter') {
             e.preventDefault();
@@ -81,6 +137,12 @@ export function SettingsPanel({
                     >
                         Exclusions
                     </button>
+                    <button
+                        className={`settings-tab ${activeTab === 'performance' ? 'active' : ''}`}
+                        onClick={() => setActiveTab('performance')}
+                    >
+                        Performance
+                    </button>
                     <button
                         className={`settings-tab ${activeTab === 'about' ? 'active' : ''}`}
                         onClick={() => setActiveTab('about')}
@@ -124,13 +186,53 @@ export function SettingsPanel({
                                     type="range"
                                     className="settings-slider"
                                     min={0}
-                                    max={20}
+                                    max={maxThreshold(hashAlgorithm)}
                                     step={1}
                                     value={similarityThreshold}
                                     onChange={(e) => onSimilarityThresholdChange(Number(e.target.value))}
                                 />
                             </div>
 
+                            <div className="settings-row">
+                                <label className="settings-label">Image hash</label>
+                                <select
+                                    className="settings-select"
+                                    value={hashAlgorithm}
+                                    disabled={similarityThreshold === 0}
+                                    onChange={(e) => onHashAlgorithmChange(e.target.value)}
+                                >
+                                    <option value="average">Average (fastest)</option>
+                                    <option value="difference">Difference (gradients)</option>
+                                    <option value="perception">Perception (default)</option>
+                                    <option value="extended">Extended perception (256-bit)</option>
+                                </select>
+                            </div>
+
+                            <div className="settings-row">
+                                <label className="settings-label">Image grouping</label>
+                                <select
+                                    className="settings-select"
+                                    value={clusterMode}
+                                    disabled={similarityThreshold === 0}
+                                    onChange={(e) => onClusterModeChange(e.target.value)}
+                                >
+                                    <option value="greedy">Greedy (fastest)</option>
+                                    <option value="connected">Connected (chains A~B~C)</option>
+                                    <option value="complete">Complete (all pairs within threshold)</option>
+                                </select>
+                            </div>
+
+                            <div className="settings-row">
+                                <label className="settings-label">Match rotated and mirrored images</label>
+                                <input
+                                    type="checkbox"
+                                    className="settings-checkbox"
+                                    checked={matchTransforms}
+                                    disabled={similarityThreshold === 0}
+                                    onChange={(e) => onMatchTransformsChange(e.target.checked)}
+                                />
+                            </div>
+
                             <div className="settings-row">
                                 <label className="settings-label">Skip hidden files</label>
                                 <input
@@ -181,6 +283,82 @@ export function SettingsPanel({
                                     </ul>
                                 )}
                             </div>
+                            <div className="settings-row settings-row-col">
+                                <label className="settings-label">Reference directories</label>
+                                <span className="settings-hint">
+                                    Files here are matched against but never trashed or replaced.
+                                </span>
+                                <div className="exclusion-input-row">
+                                    <button className="btn btn-secondary" onClick={addReferencePath}>
+                                        + Add Directory
+                                    </button>
+                                </div>
+                                {referencePaths.length > 0 && (
+                                    <ul className="path-list exclusion-list">
+                                        {referencePaths.map((dir, i) => (
+                                            <li key={dir} className="path-item">
+                                                <span className="path-text">{dir}</span>
+                                                <button
+                                                    className="btn-remove"
+                                                    onClick={() => removeReferencePath(i)}
+                                                    title="Remove"
+                                                >
+                                                    &times;
+                                                </button>
+                                            </li>
+                                        ))}
+                                    </ul>
+                                )}
+                            </div>
+                        </div>
+                    )}
+
+                    {activeTab === 'performance' && (
+                        <div className="settings-grid">
+                            <p className="settings-hint">
+                                Files read at once per disk while hashing. Spinning disks are read in on-disk
+                                order and are fastest with one reader; 0 uses the default.
+                            </p>
+                            {ioLimitRows.map(({ key, label }) => (
+                                <div className="settings-row" key={key}>
+                                    <label className="settings-label">{label}</label>
+                                    <input
+                                        type="number"
+                                        className="settings-input"
+                                        value={ioLimits[key] ?? 0}
+                                        min={0}
+                                        max={256}
+                                        onChange={(e) => onIOLimitsChange(new models.IOLimits({
+                                            ...ioLimits,
+                                            [key]: Math.max(0, Math.floor(Number(e.target.value))),
+                                        }))}
+                                    />
+                                </div>
+                            ))}
+
+                            <p className="settings-hint">
+                                Keep scans from slowing other programs down. The read rate can be changed
+                                while a scan is running.
+                            </p>
+                            <div className="settings-row">
+                                <label className="settings-label">Max read rate (MB/s, 0 = unlimited)</label>
+                                <input
+                                    type="number"
+                                    className="settings-input"
+                                    value={Math.round(maxReadRate / MB)}
+                                    min={0}
+                                    onChange={(e) => onMaxReadRateChange(Math.max(0, Math.floor(Number(e.target.value))) * MB)}
+                                />
+                            </div>
+                            <div className="settings-row">
+                                <label className="settings-label">Idle I/O priority (Linux)</label>
+                                <input
+                                    type="checkbox"
+                                    className="settings-checkbox"
+                                    checked={idleIOPriority}
+                                    onChange={(e) => onIdleIOPriorityChange(e.target.checked)}
+                                />
+                            </div>
                         </div>
                     )}
 
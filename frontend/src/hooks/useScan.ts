@@ -1,6 +1,6 @@
 import { useState, useEffect, useCallback } from 'react';
 import { EventsOn, EventsOff } from '../../wailsjs/runtime/runtime';
-import { StartScan, CancelScan } from '../../wailsjs/go/main/App';
+import { StartScan, StartScanFromCheckpoint, CancelScan, PauseScan, ResumeScan } from '../../wailsjs/go/main/App';
 import { models } from '../../wailsjs/go/models';
 import { ScanProgress, ScanStatus } from '../types';
 
@@ -37,13 +37,13 @@ export function useScan() {
         };
     }, []);
 
-    const startScan = useCallback(async (settings: models.ScanSettings) => {
+    const startScan = useCallback(async (settings: models.ScanSettings, fromCheckpoint = false) => {
         setStatus('scanning');
         setProgress({ stage: '', processed: 0, total: 0 });
         setDuplicateCount(0);
         setError('');
         try {
-            await StartScan(settings);
+            await (fromCheckpoint ? StartScanFromCheckpoint(settings) : StartScan(settings));
         } catch (e: any) {
             setError(e?.message || String(e));
             setStatus('error');
@@ -54,6 +54,14 @@ export function useScan() {
         await CancelScan();
     }, []);
 
+    const pauseScan = useCallback(async () => {
+        await PauseScan();
+    }, []);
+
+    const resumeScan = useCallback(async () => {
+        await ResumeScan();
+    }, []);
+
     const reset = useCallback(() => {
         setStatus('idle');
         setProgress({ stage: '', processed: 0, total: 0 });
@@ -61,5 +69,5 @@ export function useScan() {
         setError('');
     }, []);
 
-    return { status, progress, duplicateCount, error, startScan, cancelScan, reset };
+    return { status, progress, duplicateCount, error, startScan, cancelScan, pauseScan, resumeScan, reset };
 }
@@ -2,13 +2,35 @@ export interface ScanProgress {
     stage: string;
     processed: number;
     total: number;
+    paused?: boolean;
+    bytes_done?: number;
+    bytes_total?: number;
+    throughput?: number; // bytes per second
+    eta?: number; // seconds, 0 if unknown
+    current_file?: string;
 }
 
 export type ScanStatus = 'idle' | 'scanning' | 'complete' | 'error' | 'cancelled';
 
 export const STAGE_LABELS: Record<string, string> = {
     'walking': 'Discovering files',
+    'resuming': 'Checking files from the interrupted scan',
     'partial-hashing': 'Quick-checking candidates',
     'full-hashing': 'Verifying duplicates',
     'perceptual-hashing': 'Analyzing images',
 };
+
+export interface SkippedFile {
+    path: string;
+    reason: string;
+    stage: string;
+    error: string;
+}
+
+export const SKIP_REASON_LABELS: Record<string, string> = {
+    'permission_denied': 'Permission denied',
+    'io_error': 'I/O error',
+    'undecodable': 'Could not decode image',
+    'vanished': 'Vanished during scan',
+    'changed': 'Changed during scan',
+};
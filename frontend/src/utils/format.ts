@@ -6,6 +6,13 @@ export function formatSize(bytes: number): string {
     return `${size < 10 && i > 0 ? size.toFixed(1) : Math.round(size)} ${units[i]}`;
 }
 
+export function formatDuration(seconds: number): string {
+    const s = Math.max(1, Math.round(seconds));
+    if (s < 60) return `${s}s`;
+    if (s < 3600) return `${Math.floor(s / 60)}m ${s % 60}s`;
+    return `${Math.floor(s / 3600)}h ${Math.floor((s % 3600) / 60)}m`;
+}
+
 export function formatDate(unixSeconds: number): string {
     const d = new Date(unixSeconds * 1000);
     return d.toLocaleDateString(undefined, {
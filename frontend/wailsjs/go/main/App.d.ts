@@ -5,24 +5,56 @@ import {main} from '../models';
 
 export function CancelScan():Promise<void>;
 
+export function ClearHashCache():Promise<void>;
+
+export function DedupeFiles(arg1:string,arg2:string,arg3:Array<string>):Promise<models.DeleteOperation>;
+
 export function DeleteFiles(arg1:Array<string>):Promise<models.DeleteOperation>;
 
+export function ExportResults(arg1:string):Promise<string>;
+
 export function GetBuildInfo():Promise<main.BuildInfo>;
 
 export function GetDuplicateGroups():Promise<Array<models.DuplicateGroup>>;
 
+export function GetHashCacheStats():Promise<models.CacheStats>;
+
 export function GetOperationHistory():Promise<Array<models.DeleteOperation>>;
 
+export function GetScanCheckpoint(arg1:models.ScanSettings):Promise<models.ScanCheckpoint>;
+
+export function GetScanStats():Promise<models.ScanStats>;
+
 export function GetSettings():Promise<models.ScanSettings>;
 
+export function GetSkippedFiles():Promise<Array<models.SkippedFile>>;
+
 export function GetVersion():Promise<string>;
 
 export function OpenFile(arg1:string):Promise<void>;
 
 export function OpenFolder(arg1:string):Promise<void>;
 
+export function PauseScan():Promise<void>;
+
+export function ProposeSelection(arg1:Array<models.SelectionRule>):Promise<Array<models.Selection>>;
+
+export function QueryJournal(arg1:models.JournalFilter):Promise<Array<models.DeleteOperation>>;
+
+export function ReplaceWithHardLinks(arg1:string,arg2:string,arg3:Array<string>):Promise<models.DeleteOperation>;
+
+export function ReplaceWithSymlinks(arg1:string,arg2:string,arg3:Array<string>,arg4:models.SymlinkOptions):Promise<models.DeleteOperation>;
+
+export function ResumeScan():Promise<void>;
+
 export function SaveSettings(arg1:models.ScanSettings):Promise<void>;
 
 export function SelectDirectory():Promise<string>;
 
+export function SetReadRateLimit(arg1:number):Promise<void>;
+
 export function StartScan(arg1:models.ScanSettings):Promise<void>;
+
+export function StartScanFromCheckpoint(arg1:models.ScanSettings):Promise<void>;
+
+export function UndoOperation(arg1:string):Promise<models.UndoResult>;
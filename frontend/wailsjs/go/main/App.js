@@ -6,10 +6,22 @@ export function CancelScan() {
   return window['go']['main']['App']['CancelScan']();
 }
 
+export function ClearHashCache() {
+  return window['go']['main']['App']['ClearHashCache']();
+}
+
+export function DedupeFiles(arg1,arg2,arg3) {
+  return window['go']['main']['App']['DedupeFiles'](arg1,arg2,arg3);
+}
+
 export function DeleteFiles(arg1) {
   return window['go']['main']['App']['DeleteFiles'](arg1);
 }
 
+export function ExportResults(arg1) {
+  return window['go']['main']['App']['ExportResults'](arg1);
+}
+
 export function GetBuildInfo() {
   return window['go']['main']['App']['GetBuildInfo']();
 }
@@ -18,14 +30,30 @@ export function GetDuplicateGroups() {
   return window['go']['main']['App']['GetDuplicateGroups']();
 }
 
+export function GetHashCacheStats() {
+  return window['go']['main']['App']['GetHashCacheStats']();
+}
+
 export function GetOperationHistory() {
   return window['go']['main']['App']['GetOperationHistory']();
 }
 
+export function GetScanCheckpoint(arg1) {
+  return window['go']['main']['App']['GetScanCheckpoint'](arg1);
+}
+
+export function GetScanStats() {
+  return window['go']['main']['App']['GetScanStats']();
+}
+
 export function GetSettings() {
   return window['go']['main']['App']['GetSettings']();
 }
 
+export function GetSkippedFiles() {
+  return window['go']['main']['App']['GetSkippedFiles']();
+}
+
 export function GetVersion() {
   return window['go']['main']['App']['GetVersion']();
 }
@@ -38,6 +66,30 @@ export function OpenFolder(arg1) {
   return window['go']['main']['App']['OpenFolder'](arg1);
 }
 
+export function PauseScan() {
+  return window['go']['main']['App']['PauseScan']();
+}
+
+export function ProposeSelection(arg1) {
+  return window['go']['main']['App']['ProposeSelection'](arg1);
+}
+
+export function QueryJournal(arg1) {
+  return window['go']['main']['App']['QueryJournal'](arg1);
+}
+
+export function ReplaceWithHardLinks(arg1,arg2,arg3) {
+  return window['go']['main']['App']['ReplaceWithHardLinks'](arg1,arg2,arg3);
+}
+
+export function ReplaceWithSymlinks(arg1,arg2,arg3,arg4) {
+  return window['go']['main']['App']['ReplaceWithSymlinks'](arg1,arg2,arg3,arg4);
+}
+
+export function ResumeScan() {
+  return window['go']['main']['App']['ResumeScan']();
+}
+
 export function SaveSettings(arg1) {
   return window['go']['main']['App']['SaveSettings'](arg1);
 }
@@ -46,6 +98,18 @@ export function SelectDirectory() {
   return window['go']['main']['App']['SelectDirectory']();
 }
 
+export function SetReadRateLimit(arg1) {
+  return window['go']['main']['App']['SetReadRateLimit'](arg1);
+}
+
 export function StartScan(arg1) {
   return window['go']['main']['App']['StartScan'](arg1);
 }
+
+export function StartScanFromCheckpoint(arg1) {
+  return window['go']['main']['App']['StartScanFromCheckpoint'](arg1);
+}
+
+export function UndoOperation(arg1) {
+  return window['go']['main']['App']['UndoOperation'](arg1);
+}
@@ -23,6 +23,22 @@ export namespace main {
 
 export namespace models {
 	
+	export class CacheStats {
+	    path: string;
+	    entries: number;
+	    size_bytes: number;
+	
+	    static createFrom(source: any = {}) {
+	        return new CacheStats(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.path = source["path"];
+	        this.entries = source["entries"];
+	        this.size_bytes = source["size_bytes"];
+	    }
+	}
 	export class FailedDelete {
 	    path: string;
 	    reason: string;
@@ -37,11 +53,90 @@ export namespace models {
 	        this.reason = source["reason"];
 	    }
 	}
+	export class Removal {
+	    path: string;
+	    group_id: string;
+	    hash: string;
+	    kept_path: string;
+	
+	    static createFrom(source: any = {}) {
+	        return new Removal(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.path = source["path"];
+	        this.group_id = source["group_id"];
+	        this.hash = source["hash"];
+	        this.kept_path = source["kept_path"];
+	    }
+	}
+	export class FileMeta {
+	    mode: number;
+	    modified: number;
+	    uid: number;
+	    gid: number;
+	
+	    static createFrom(source: any = {}) {
+	        return new FileMeta(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.mode = source["mode"];
+	        this.modified = source["modified"];
+	        this.uid = source["uid"];
+	        this.gid = source["gid"];
+	    }
+	}
+	export class ReplacedFile {
+	    path: string;
+	    target: string;
+	    backup_path?: string;
+	    original?: FileMeta;
+	
+	    static createFrom(source: any = {}) {
+	        return new ReplacedFile(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.path = source["path"];
+	        this.target = source["target"];
+	        this.backup_path = source["backup_path"];
+	        this.original = this.convertValues(source["original"], FileMeta);
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
 	export class DeleteOperation {
 	    id: string;
+	    kind: string;
 	    deleted_paths: string[];
+	    replaced?: ReplacedFile[];
+	    removals?: Removal[];
 	    failed_paths: FailedDelete[];
 	    timestamp: string;
+	    started?: string;
+	    undone: boolean;
+	    undone_paths?: string[];
+	    reclaimed_bytes?: number;
 	
 	    static createFrom(source: any = {}) {
 	        return new DeleteOperation(source);
@@ -50,9 +145,16 @@ export namespace models {
 	    constructor(source: any = {}) {
 	        if ('string' === typeof source) source = JSON.parse(source);
 	        this.id = source["id"];
+	        this.kind = source["kind"];
 	        this.deleted_paths = source["deleted_paths"];
+	        this.replaced = this.convertValues(source["replaced"], ReplacedFile);
+	        this.removals = this.convertValues(source["removals"], Removal);
 	        this.failed_paths = this.convertValues(source["failed_paths"], FailedDelete);
 	        this.timestamp = source["timestamp"];
+	        this.started = source["started"];
+	        this.undone = source["undone"];
+	        this.undone_paths = source["undone_paths"];
+	        this.reclaimed_bytes = source["reclaimed_bytes"];
 	    }
 	
 		convertValues(a: any, classs: any, asMap: boolean = false): any {
@@ -79,9 +181,16 @@ export namespace models {
 	    name: string;
 	    extension: string;
 	    modified: number;
+	    device: number;
+	    inode: number;
+	    hard_link_of?: string;
+	    read_only?: boolean;
 	    partial_hash: string;
 	    full_hash: string;
 	    perceptual_hash: string;
+	    distance?: number;
+	    similarity?: number;
+	    transform?: string;
 	
 	    static createFrom(source: any = {}) {
 	        return new FileInfo(source);
@@ -94,15 +203,39 @@ export namespace models {
 	        this.name = source["name"];
 	        this.extension = source["extension"];
 	        this.modified = source["modified"];
+	        this.device = source["device"];
+	        this.inode = source["inode"];
+	        this.hard_link_of = source["hard_link_of"];
+	        this.read_only = source["read_only"];
 	        this.partial_hash = source["partial_hash"];
 	        this.full_hash = source["full_hash"];
 	        this.perceptual_hash = source["perceptual_hash"];
+	        this.distance = source["distance"];
+	        this.similarity = source["similarity"];
+	        this.transform = source["transform"];
+	    }
+	}
+	export class JournalFilter {
+	    since: string;
+	    until: string;
+	    path_prefix: string;
+	
+	    static createFrom(source: any = {}) {
+	        return new JournalFilter(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.since = source["since"];
+	        this.until = source["until"];
+	        this.path_prefix = source["path_prefix"];
 	    }
 	}
 	export class DuplicateGroup {
 	    id: string;
 	    kind: string;
 	    similarity: number;
+	    max_distance: number;
 	    files: FileInfo[];
 	    total_size: number;
 	    wasted_size: number;
@@ -116,6 +249,7 @@ export namespace models {
 	        this.id = source["id"];
 	        this.kind = source["kind"];
 	        this.similarity = source["similarity"];
+	        this.max_distance = source["max_distance"];
 	        this.files = this.convertValues(source["files"], FileInfo);
 	        this.total_size = source["total_size"];
 	        this.wasted_size = source["wasted_size"];
@@ -141,6 +275,24 @@ export namespace models {
 	}
 	
 	
+	export class IOLimits {
+	    rotational: number;
+	    ssd: number;
+	    nvme: number;
+	    unknown: number;
+	
+	    static createFrom(source: any = {}) {
+	        return new IOLimits(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.rotational = source["rotational"];
+	        this.ssd = source["ssd"];
+	        this.nvme = source["nvme"];
+	        this.unknown = source["unknown"];
+	    }
+	}
 	export class ScanSettings {
 	    paths: string[];
 	    min_file_size: number;
@@ -148,6 +300,13 @@ export namespace models {
 	    excluded_dirs: string[];
 	    similarity_threshold: number;
 	    skip_hidden: boolean;
+	    cluster_mode: string;
+	    hash_algorithm: string;
+	    reference_paths: string[];
+	    match_transforms: boolean;
+	    io_limits: IOLimits;
+	    max_read_rate: number;
+	    idle_io_priority: boolean;
 	
 	    static createFrom(source: any = {}) {
 	        return new ScanSettings(source);
@@ -161,6 +320,167 @@ export namespace models {
 	        this.excluded_dirs = source["excluded_dirs"];
 	        this.similarity_threshold = source["similarity_threshold"];
 	        this.skip_hidden = source["skip_hidden"];
+	        this.cluster_mode = source["cluster_mode"];
+	        this.hash_algorithm = source["hash_algorithm"];
+	        this.reference_paths = source["reference_paths"];
+	        this.match_transforms = source["match_transforms"];
+	        this.io_limits = this.convertValues(source["io_limits"], IOLimits);
+	        this.max_read_rate = source["max_read_rate"];
+	        this.idle_io_priority = source["idle_io_priority"];
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	export class SymlinkOptions {
+	    relative: boolean;
+	    backup_dir: string;
+	
+	    static createFrom(source: any = {}) {
+	        return new SymlinkOptions(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.relative = source["relative"];
+	        this.backup_dir = source["backup_dir"];
+	    }
+	}
+	export class UndoResult {
+	    operation_id: string;
+	    restored_paths: string[];
+	    failed_paths: FailedDelete[];
+	    renamed?: {[key: string]: string};
+	
+	    static createFrom(source: any = {}) {
+	        return new UndoResult(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.operation_id = source["operation_id"];
+	        this.restored_paths = source["restored_paths"];
+	        this.failed_paths = this.convertValues(source["failed_paths"], FailedDelete);
+	        this.renamed = source["renamed"];
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	export class SelectionRule {
+	    type: string;
+	    value?: string;
+	
+	    static createFrom(source: any = {}) {
+	        return new SelectionRule(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.type = source["type"];
+	        this.value = source["value"];
+	    }
+	}
+	export class Selection {
+	    group_id: string;
+	    keep: string;
+	    remove: string[];
+	    reason: string;
+	
+	    static createFrom(source: any = {}) {
+	        return new Selection(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.group_id = source["group_id"];
+	        this.keep = source["keep"];
+	        this.remove = source["remove"];
+	        this.reason = source["reason"];
+	    }
+	}
+	export class ScanStats {
+	    files_scanned: number;
+	    images_hashed: number;
+	    undecodable_images: number;
+	    files_skipped: number;
+	
+	    static createFrom(source: any = {}) {
+	        return new ScanStats(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.files_scanned = source["files_scanned"];
+	        this.images_hashed = source["images_hashed"];
+	        this.undecodable_images = source["undecodable_images"];
+	        this.files_skipped = source["files_skipped"];
+	    }
+	}
+	export class ScanCheckpoint {
+	    stage: string;
+	    files_scanned: number;
+	    files_hashed: number;
+	    saved: number;
+	
+	    static createFrom(source: any = {}) {
+	        return new ScanCheckpoint(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.stage = source["stage"];
+	        this.files_scanned = source["files_scanned"];
+	        this.files_hashed = source["files_hashed"];
+	        this.saved = source["saved"];
+	    }
+	}
+	export class SkippedFile {
+	    path: string;
+	    reason: string;
+	    stage: string;
+	    error: string;
+	
+	    static createFrom(source: any = {}) {
+	        return new SkippedFile(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.path = source["path"];
+	        this.reason = source["reason"];
+	        this.stage = source["stage"];
+	        this.error = source["error"];
 	    }
 	}
 
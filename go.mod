@@ -11,7 +11,9 @@ require (
 	github.com/google/uuid v1.6.0
 	github.com/wailsapp/wails/v2 v2.11.0
 	github.com/zeebo/blake3 v0.2.4
+	golang.org/x/image v0.25.0
 	golang.org/x/sync v0.19.0
+	golang.org/x/sys v0.30.0
 )
 
 require (
@@ -42,7 +44,6 @@ require (
 	github.com/wailsapp/mimetype v1.4.1 // indirect
 	golang.org/x/crypto v0.33.0 // indirect
 	golang.org/x/net v0.35.0 // indirect
-	golang.org/x/sys v0.30.0 // indirect
 	golang.org/x/text v0.34.0 // indirect
 )
 
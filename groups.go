@@ -0,0 +1,221 @@
+package main
+
+import (
+	"fmt"
+
+	"folder-cleaner-go/models"
+	"folder-cleaner-go/scanner"
+)
+
+// removedFile remembers a file taken out of a group so an undo can put it back.
+type removedFile struct {
+	groupID string
+	file    models.FileInfo
+}
+
+// removeFromGroups drops the given paths from all groups, remembering them
+// under opID, and prunes groups left with nothing to reclaim.
+// Callers must hold a.mu.
+func (a *App) removeFromGroups(opID string, paths []string) {
+	removed := make(map[string]bool, len(paths))
+	for _, p := range paths {
+		removed[p] = true
+	}
+	for i := range a.groups {
+		g := &a.groups[i]
+		var remaining []models.FileInfo
+		for _, f := range g.Files {
+			if !removed[f.Path] {
+				remaining = append(remaining, f)
+				continue
+			}
+			if a.removed == nil {
+				a.removed = make(map[string][]removedFile)
+			}
+			a.removed[opID] = append(a.removed[opID], removedFile{groupID: g.ID, file: f})
+		}
+		g.Files = remaining
+	}
+	a.regroup()
+}
+
+// describeRemovals records, for each path about to leave its group, the group,
+// the hash it matched on and the file being kept: keepPath if given, otherwise
+// the first file of the group that is not being removed. Callers must hold a.mu.
+func (a *App) describeRemovals(paths []string, keepPath string) []models.Removal {
+	removing := make(map[string]bool, len(paths))
+	for _, p := range paths {
+		removing[p] = true
+	}
+
+	var removals []models.Removal
+	for _, g := range a.groups {
+		kept := keepPath
+		if kept == "" {
+			for _, f := range g.Files {
+				if !removing[f.Path] {
+					kept = f.Path
+					break
+				}
+			}
+		}
+		for _, f := range g.Files {
+			if !removing[f.Path] {
+				continue
+			}
+			hash := f.FullHash
+			if g.Kind == models.KindSimilar {
+				hash = f.PerceptualHash
+			}
+			removals = append(removals, models.Removal{Path: f.Path, GroupID: g.ID, Hash: hash, KeptPath: kept})
+		}
+	}
+	return removals
+}
+
+// regroup recalculates every group and moves groups between the visible list
+// and the pruned set depending on whether they still hold two or more copies.
+// Callers must hold a.mu.
+func (a *App) regroup() {
+	filtered := a.groups[:0]
+	for _, g := range a.groups {
+		g.Recalculate()
+		if g.Copies() > 1 {
+			filtered = append(filtered, g)
+			continue
+		}
+		if a.dropped == nil {
+			a.dropped = make(map[string]models.DuplicateGroup)
+		}
+		a.dropped[g.ID] = g
+	}
+	a.groups = filtered
+
+	for id, g := range a.dropped {
+		g.Recalculate()
+		if g.Copies() > 1 {
+			a.groups = append(a.groups, g)
+			delete(a.dropped, id)
+		}
+	}
+}
+
+// restoreToGroups puts the files restored by undoing opID back into their
+// groups, reviving groups that had been pruned. Files still listed in a group
+// (e.g. after breaking hard links) just get their metadata refreshed.
+// Callers must hold a.mu.
+func (a *App) restoreToGroups(opID string, result *models.UndoResult) {
+	stashed := make(map[string]removedFile, len(a.removed[opID]))
+	for _, r := range a.removed[opID] {
+		stashed[r.file.Path] = r
+	}
+
+	restored := make(map[string]bool, len(result.RestoredPaths))
+	for i, orig := range result.Originals() {
+		restored[orig] = true
+		p := result.RestoredPaths[i]
+		info, err := scanner.StatFile(p)
+		if err != nil {
+			continue
+		}
+
+		if a.refreshInGroups(orig, info) {
+			continue
+		}
+		r, ok := stashed[orig]
+		if !ok {
+			continue
+		}
+		f := r.file
+		f.Path, f.Name, f.Modified, f.ModifiedNano = info.Path, info.Name, info.Modified, info.ModifiedNano
+		f.Device, f.Inode = info.Device, info.Inode
+		a.addToGroup(r.groupID, f)
+	}
+
+	// Files that were not restored stay stashed for a later retry
+	kept := a.removed[opID][:0]
+	for _, r := range a.removed[opID] {
+		if !restored[r.file.Path] {
+			kept = append(kept, r)
+		}
+	}
+	if len(kept) == 0 {
+		delete(a.removed, opID)
+	} else {
+		a.removed[opID] = kept
+	}
+	a.regroup()
+}
+
+// refreshInGroups updates the on-disk identity of the file at path wherever
+// it appears, reporting whether it was found. Callers must hold a.mu.
+func (a *App) refreshInGroups(path string, info models.FileInfo) bool {
+	found := false
+	update := func(files []models.FileInfo) {
+		for i := range files {
+			if files[i].Path == path {
+				files[i].Modified, files[i].ModifiedNano = info.Modified, info.ModifiedNano
+				files[i].Device, files[i].Inode = info.Device, info.Inode
+				found = true
+			}
+		}
+	}
+	for _, g := range a.groups {
+		update(g.Files)
+	}
+	for _, g := range a.dropped {
+		update(g.Files)
+	}
+	return found
+}
+
+// addToGroup appends f to the group with the given ID, whether visible or
+// pruned. Callers must hold a.mu.
+func (a *App) addToGroup(groupID string, f models.FileInfo) {
+	for i := range a.groups {
+		if a.groups[i].ID == groupID {
+			a.groups[i].Files = append(a.groups[i].Files, f)
+			return
+		}
+	}
+	if g, ok := a.dropped[groupID]; ok {
+		g.Files = append(g.Files, f)
+		a.dropped[groupID] = g
+	}
+}
+
+// exactGroup returns the exact group with the given ID after checking that
+// keepPath and paths all belong to it. Callers must hold a.mu.
+func (a *App) exactGroup(groupID, keepPath string, paths []string) (models.DuplicateGroup, error) {
+	for _, g := range a.groups {
+		if g.ID != groupID {
+			continue
+		}
+		if g.Kind != models.KindExact {
+			return g, fmt.Errorf("only exact duplicate groups can be replaced")
+		}
+		members := make(map[string]bool, len(g.Files))
+		readOnly := make(map[string]bool)
+		for _, f := range g.Files {
+			members[f.Path] = true
+			readOnly[f.Path] = f.ReadOnly
+		}
+		if !members[keepPath] {
+			return g, fmt.Errorf("kept file is not in the group")
+		}
+		protected := a.protectedPaths()
+		for _, p := range paths {
+			if !members[p] {
+				return g, fmt.Errorf("%s is not in the group", p)
+			}
+			if p == keepPath {
+				return g, fmt.Errorf("cannot replace the kept file with a link to itself")
+			}
+			if readOnly[p] || models.IsUnderAny(p, protected) {
+				return g, fmt.Errorf("%s is in a protected reference directory", p)
+			}
+		}
+		return g, nil
+	}
+	return models.DuplicateGroup{}, fmt.Errorf("group not found")
+}
@@ -2,6 +2,9 @@ package main
 
 import (
 	"embed"
+	"os"
+
+	"folder-cleaner-go/cli"
 
 	"github.com/wailsapp/wails/v2"
 	"github.com/wailsapp/wails/v2/pkg/options"
@@ -20,6 +23,11 @@ var (
 )
 
 func main() {
+	// Headless mode: `ShadowWipe scan ...` runs the pipeline without the UI.
+	if len(os.Args) > 1 && cli.IsCommand(os.Args[1]) {
+		os.Exit(cli.Main(os.Args[1:]))
+	}
+
 	app := NewApp()
 
 	err := wails.Run(&options.App{
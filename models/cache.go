@@ -0,0 +1,8 @@
+package models
+
+// CacheStats describes the on-disk hash cache.
+type CacheStats struct {
+	Path      string `json:"path"`
+	Entries   int    `json:"entries"`
+	SizeBytes int64  `json:"size_bytes"` // size of the cache file on disk
+}
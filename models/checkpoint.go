@@ -0,0 +1,10 @@
+package models
+
+// ScanCheckpoint describes the saved progress of an unfinished scan, which a
+// new scan with the same settings can resume.
+type ScanCheckpoint struct {
+	Stage        string `json:"stage"` // earliest hashing stage that had not finished
+	FilesScanned int    `json:"files_scanned"`
+	FilesHashed  int    `json:"files_hashed"` // files with at least one hash saved
+	Saved        int64  `json:"saved"`        // Unix time of the last save; 0 if only the walk was saved
+}
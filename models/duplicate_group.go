@@ -12,8 +12,58 @@ const (
 type DuplicateGroup struct {
 	ID         string        `json:"id"`
 	Kind       DuplicateKind `json:"kind"`
-	Similarity float64       `json:"similarity"` // 0 for exact, 0-100 for similar
-	Files      []FileInfo    `json:"files"`
-	TotalSize  int64         `json:"total_size"`
-	WastedSize int64         `json:"wasted_size"`
+	Similarity float64       `json:"similarity"` // 0 for exact; for similar, the least similar file's Similarity
+	// MaxDistance is the largest Hamming distance between any two perceptual
+	// hashes in a similar group; 0 for exact groups.
+	MaxDistance int        `json:"max_distance"`
+	Files       []FileInfo `json:"files"`
+	TotalSize   int64      `json:"total_size"`  // bytes on disk; hard links count once
+	WastedSize  int64      `json:"wasted_size"` // bytes reclaimable by keeping one copy
+}
+
+// Recalculate refreshes hard-link flags and size totals from Files. Hard links
+// to the same inode are counted once, since removing one of them frees nothing,
+// and read-only reference copies are never counted as wasted.
+func (g *DuplicateGroup) Recalculate() {
+	type fileKey struct{ dev, ino uint64 }
+	seen := make(map[fileKey]string, len(g.Files))
+
+	var totalSize, referenceSize int64
+	for i := range g.Files {
+		f := &g.Files[i]
+		f.HardLinkOf = ""
+		if f.Inode != 0 {
+			k := fileKey{f.Device, f.Inode}
+			if first, ok := seen[k]; ok {
+				f.HardLinkOf = first
+				continue
+			}
+			seen[k] = f.Path
+		}
+		totalSize += f.Size
+		if f.ReadOnly {
+			referenceSize += f.Size
+		}
+	}
+
+	g.TotalSize = totalSize
+	g.WastedSize = 0
+	switch {
+	case referenceSize > 0:
+		g.WastedSize = totalSize - referenceSize // reference copies are always kept
+	case len(g.Files) > 0:
+		g.WastedSize = totalSize - g.Files[0].Size // all copies minus one
+	}
+}
+
+// Copies returns the number of distinct files in the group, not counting
+// extra hard links. A group with fewer than two copies has nothing to reclaim.
+func (g DuplicateGroup) Copies() int {
+	n := 0
+	for _, f := range g.Files {
+		if f.HardLinkOf == "" {
+			n++
+		}
+	}
+	return n
 }
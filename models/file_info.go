@@ -2,12 +2,72 @@ package models
 
 // FileInfo holds metadata about a single file discovered during scanning.
 type FileInfo struct {
-	Path           string `json:"path"`
-	Size           int64  `json:"size"`
-	Name           string `json:"name"`
-	Extension      string `json:"extension"`
-	Modified       int64  `json:"modified"` // Unix timestamp
+	Path      string `json:"path"`
+	Size      int64  `json:"size"`
+	Name      string `json:"name"`
+	Extension string `json:"extension"`
+	Modified  int64  `json:"modified"` // Unix timestamp
+	// ModifiedNano is Modified in Unix nanoseconds, to tell apart rewrites
+	// within the same second when checking whether hashes are still valid.
+	ModifiedNano   int64  `json:"-"`
+	Device         uint64 `json:"device"`
+	Inode          uint64 `json:"inode"`                  // 0 where the platform does not expose one
+	HardLinkOf     string `json:"hard_link_of,omitempty"` // path in the same group sharing this inode
+	ReadOnly       bool   `json:"read_only,omitempty"`    // under a reference path; never deleted or replaced
 	PartialHash    string `json:"partial_hash"`
 	FullHash       string `json:"full_hash"`
 	PerceptualHash string `json:"perceptual_hash"`
+	// Distance and Similarity compare the file with its similar group's
+	// representative (the group's first file): Hamming distance between the
+	// perceptual hashes and the share of matching bits, 0-100. Unset for
+	// exact groups.
+	Distance   int     `json:"distance,omitempty"`
+	Similarity float64 `json:"similarity,omitempty"`
+	// Transform is how the file is rotated or mirrored relative to its
+	// similar group's representative, when that gave the closest match.
+	Transform ImageTransform `json:"transform,omitempty"`
+}
+
+// ImageTransform names a rotation or mirroring between two similar images.
+type ImageTransform string
+
+const (
+	TransformNone      ImageTransform = ""
+	TransformRotate90  ImageTransform = "rotate90" // clockwise
+	TransformRotate180 ImageTransform = "rotate180"
+	TransformRotate270 ImageTransform = "rotate270" // clockwise, i.e. 90 anticlockwise
+	TransformFlip      ImageTransform = "flip"      // mirrored left to right
+)
+
+// Inverse returns the transform that undoes t.
+func (t ImageTransform) Inverse() ImageTransform {
+	switch t {
+	case TransformRotate90:
+		return TransformRotate270
+	case TransformRotate270:
+		return TransformRotate90
+	default:
+		return t
+	}
+}
+
+// Label describes t for display, e.g. "rotated 90°"; empty for TransformNone.
+func (t ImageTransform) Label() string {
+	switch t {
+	case TransformRotate90:
+		return "rotated 90°"
+	case TransformRotate180:
+		return "rotated 180°"
+	case TransformRotate270:
+		return "rotated 270°"
+	case TransformFlip:
+		return "mirrored"
+	default:
+		return ""
+	}
+}
+
+// SameData reports whether f and o are hard links to the same inode.
+func (f FileInfo) SameData(o FileInfo) bool {
+	return f.Inode != 0 && f.Inode == o.Inode && f.Device == o.Device
 }
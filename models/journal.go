@@ -0,0 +1,191 @@
+package models
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// Journal event types.
+const (
+	JournalOperation = "operation"
+	JournalUndo      = "undo"
+)
+
+// JournalEntry is one line of the append-only operation journal.
+type JournalEntry struct {
+	Event       string           `json:"event"`
+	Operation   *DeleteOperation `json:"operation,omitempty"`    // for JournalOperation
+	OperationID string           `json:"operation_id,omitempty"` // for JournalUndo
+	// Paths are the paths a JournalUndo restored. Entries written before
+	// partial undos were recorded have none, meaning all of them.
+	Paths     []string `json:"paths,omitempty"`
+	Timestamp string   `json:"timestamp"` // ISO 8601
+}
+
+// JournalFilter selects operations from the journal. Empty fields match
+// everything. Since and Until accept RFC 3339 timestamps or YYYY-MM-DD dates
+// (Until is inclusive of the whole day). PathPrefix matches operations on a
+// path inside that directory, or on the path itself.
+type JournalFilter struct {
+	Since      string `json:"since"`
+	Until      string `json:"until"`
+	PathPrefix string `json:"path_prefix"`
+}
+
+// journalPath returns the path to the journal file, next to settings.json.
+func journalPath() (string, error) {
+	p, err := settingsPath()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(filepath.Dir(p), "journal.jsonl"), nil
+}
+
+// AppendJournal appends one entry to the journal, creating it if needed.
+func AppendJournal(e JournalEntry) error {
+	p, err := journalPath()
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(p), 0o755); err != nil {
+		return err
+	}
+
+	data, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+
+	f, err := os.OpenFile(p, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0o644)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(append(data, '\n')); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// LoadJournal replays the journal into the list of recorded operations, oldest
+// first, with undo events applied. A missing journal yields no operations;
+// unreadable lines (e.g. from a crash mid-write) are skipped.
+func LoadJournal() ([]DeleteOperation, error) {
+	p, err := journalPath()
+	if err != nil {
+		return nil, err
+	}
+
+	f, err := os.Open(p)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var ops []DeleteOperation
+	index := make(map[string]int)
+
+	sc := bufio.NewScanner(f)
+	sc.Buffer(make([]byte, 0, 64*1024), 64*1024*1024)
+	for sc.Scan() {
+		var e JournalEntry
+		if err := json.Unmarshal(sc.Bytes(), &e); err != nil {
+			continue
+		}
+		switch e.Event {
+		case JournalOperation:
+			if e.Operation != nil {
+				index[e.Operation.ID] = len(ops)
+				ops = append(ops, *e.Operation)
+			}
+		case JournalUndo:
+			if i, ok := index[e.OperationID]; ok {
+				if len(e.Paths) == 0 {
+					ops[i].Undone = true
+				} else {
+					ops[i].MarkUndone(e.Paths)
+				}
+			}
+		}
+	}
+	return ops, sc.Err()
+}
+
+// Filter returns the operations matching f.
+func (f JournalFilter) Filter(ops []DeleteOperation) ([]DeleteOperation, error) {
+	since, err := parseJournalTime(f.Since, false)
+	if err != nil {
+		return nil, fmt.Errorf("since: %w", err)
+	}
+	until, err := parseJournalTime(f.Until, true)
+	if err != nil {
+		return nil, fmt.Errorf("until: %w", err)
+	}
+
+	out := []DeleteOperation{}
+	for _, op := range ops {
+		ts, err := time.Parse(time.RFC3339, op.Timestamp)
+		if err != nil {
+			continue
+		}
+		if !since.IsZero() && ts.Before(since) {
+			continue
+		}
+		if !until.IsZero() && ts.After(until) {
+			continue
+		}
+		if f.PathPrefix != "" && !op.touches(f.PathPrefix) {
+			continue
+		}
+		out = append(out, op)
+	}
+	return out, nil
+}
+
+// touches reports whether any path affected by op lies inside dir, or is dir.
+func (op DeleteOperation) touches(dir string) bool {
+	for _, p := range op.DeletedPaths {
+		if IsUnder(p, dir) {
+			return true
+		}
+	}
+	for _, r := range op.Replaced {
+		if IsUnder(r.Path, dir) {
+			return true
+		}
+	}
+	for _, fd := range op.FailedPaths {
+		if IsUnder(fd.Path, dir) {
+			return true
+		}
+	}
+	return false
+}
+
+// parseJournalTime parses an RFC 3339 timestamp or a local YYYY-MM-DD date.
+// For dates, endOfDay selects the last instant of that day.
+func parseJournalTime(v string, endOfDay bool) (time.Time, error) {
+	if v == "" {
+		return time.Time{}, nil
+	}
+	if t, err := time.Parse(time.RFC3339, v); err == nil {
+		return t, nil
+	}
+	d, err := time.ParseInLocation("2006-01-02", v, time.Local)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid date %q (want YYYY-MM-DD or RFC 3339)", v)
+	}
+	if endOfDay {
+		d = d.AddDate(0, 0, 1).Add(-time.Nanosecond)
+	}
+	return d, nil
+}
@@ -1,11 +1,69 @@
 package models
 
+// OperationKind identifies how an operation reclaimed space.
+type OperationKind string
+
+const (
+	OpTrash    OperationKind = "trash"
+	OpHardLink OperationKind = "hardlink"
+	OpSymlink  OperationKind = "symlink"
+	OpDedupe   OperationKind = "dedupe"
+)
+
 // DeleteOperation records a batch of files moved to trash, enabling undo.
+// For link-based operations the affected files are listed in Replaced instead.
 type DeleteOperation struct {
 	ID           string         `json:"id"`
+	Kind         OperationKind  `json:"kind"`
 	DeletedPaths []string       `json:"deleted_paths"`
+	Replaced     []ReplacedFile `json:"replaced,omitempty"`
+	Removals     []Removal      `json:"removals,omitempty"`
 	FailedPaths  []FailedDelete `json:"failed_paths"`
-	Timestamp    string         `json:"timestamp"` // ISO 8601
+	Timestamp    string         `json:"timestamp"` // ISO 8601, when the operation finished
+	Undone       bool           `json:"undone"`
+	// Started is when the operation began, for those that move files to the
+	// trash; undo looks for trash entries between Started and Timestamp.
+	Started string `json:"started,omitempty"`
+	// UndonePaths are the paths already restored by an undo that could not
+	// restore all of them; a later undo only retries the others.
+	UndonePaths []string `json:"undone_paths,omitempty"`
+	// ReclaimedBytes is the measured free-space gain; only set for dedupe
+	// operations, where it can differ from the files' sizes.
+	ReclaimedBytes int64 `json:"reclaimed_bytes,omitempty"`
+}
+
+// Paths returns every path op removed or replaced.
+func (op DeleteOperation) Paths() []string {
+	if len(op.Replaced) == 0 {
+		return op.DeletedPaths
+	}
+	paths := make([]string, len(op.Replaced))
+	for i, r := range op.Replaced {
+		paths[i] = r.Path
+	}
+	return paths
+}
+
+// MarkUndone records that paths were restored, setting Undone once all of
+// op's paths have been.
+func (op *DeleteOperation) MarkUndone(paths []string) {
+	done := make(map[string]bool, len(op.UndonePaths)+len(paths))
+	for _, p := range op.UndonePaths {
+		done[p] = true
+	}
+	for _, p := range paths {
+		if !done[p] {
+			done[p] = true
+			op.UndonePaths = append(op.UndonePaths, p)
+		}
+	}
+	op.Undone = true
+	for _, p := range op.Paths() {
+		if !done[p] {
+			op.Undone = false
+			break
+		}
+	}
 }
 
 // FailedDelete records a file that could not be trashed and why.
@@ -13,3 +71,61 @@ type FailedDelete struct {
 	Path   string `json:"path"`
 	Reason string `json:"reason"`
 }
+
+// Removal records why a path was removed or replaced: the duplicate group it
+// belonged to, the hash that matched and the copy that was kept.
+type Removal struct {
+	Path     string `json:"path"`
+	GroupID  string `json:"group_id"`
+	Hash     string `json:"hash"`
+	KeptPath string `json:"kept_path"`
+}
+
+// ReplacedFile records a duplicate that was replaced by a link to Target.
+type ReplacedFile struct {
+	Path       string `json:"path"`
+	Target     string `json:"target"`
+	BackupPath string `json:"backup_path,omitempty"` // where the original was kept; empty if trashed
+	// Original is the duplicate's own metadata, for hard links, which share
+	// Target's; undo gives it back. Nil in operations recorded before it was.
+	Original *FileMeta `json:"original,omitempty"`
+}
+
+// FileMeta is the metadata of a file that a replacement does not preserve.
+type FileMeta struct {
+	Mode     uint32 `json:"mode"`     // permission bits, with setuid, setgid and sticky
+	Modified int64  `json:"modified"` // Unix nanoseconds
+	UID      int    `json:"uid"`      // -1 where ownership is not available
+	GID      int    `json:"gid"`
+}
+
+// SymlinkOptions controls how duplicates are replaced with symbolic links.
+type SymlinkOptions struct {
+	Relative  bool   `json:"relative"`   // link via a path relative to the duplicate's directory
+	BackupDir string `json:"backup_dir"` // keep originals here instead of the system trash
+}
+
+// UndoResult reports the outcome of reverting an operation.
+type UndoResult struct {
+	OperationID   string            `json:"operation_id"`
+	RestoredPaths []string          `json:"restored_paths"`
+	FailedPaths   []FailedDelete    `json:"failed_paths"`
+	Renamed       map[string]string `json:"renamed,omitempty"` // original path -> restored path, on name collisions
+}
+
+// Originals returns the original paths of the restored files, undoing any
+// renames.
+func (r *UndoResult) Originals() []string {
+	original := make(map[string]string, len(r.Renamed))
+	for from, to := range r.Renamed {
+		original[to] = from
+	}
+	paths := make([]string, len(r.RestoredPaths))
+	for i, p := range r.RestoredPaths {
+		if o, ok := original[p]; ok {
+			p = o
+		}
+		paths[i] = p
+	}
+	return paths
+}
@@ -0,0 +1,17 @@
+package models
+
+// ScanProgress is a progress report from a running scan.
+type ScanProgress struct {
+	Stage     string `json:"stage"`
+	Processed int    `json:"processed"`
+	Total     int    `json:"total"`  // 0 while the total is not known
+	Paused    bool   `json:"paused"` // reads are held until the scan is resumed
+
+	// Hashing reads. BytesTotal covers the files queued so far, so it grows
+	// as files pass from one stage to the next.
+	BytesDone   int64   `json:"bytes_done"`
+	BytesTotal  int64   `json:"bytes_total"`
+	Throughput  float64 `json:"throughput"`   // bytes per second over the last few seconds
+	ETA         float64 `json:"eta"`          // estimated seconds left; 0 if unknown
+	CurrentFile string  `json:"current_file"` // file most recently started
+}
@@ -0,0 +1,9 @@
+package models
+
+// ScanStats summarises what a scan looked at, beyond the groups it found.
+type ScanStats struct {
+	FilesScanned      int `json:"files_scanned"`      // files found by the walk
+	ImagesHashed      int `json:"images_hashed"`      // images given a perceptual hash
+	UndecodableImages int `json:"undecodable_images"` // images whose format was supported but could not be decoded
+	FilesSkipped      int `json:"files_skipped"`      // files and directories that could not be examined (see Scanner.Skipped)
+}
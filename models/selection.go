@@ -0,0 +1,30 @@
+package models
+
+// SelectionRuleType names a rule for choosing which file of a group to keep.
+type SelectionRuleType string
+
+const (
+	RuleKeepOldest       SelectionRuleType = "keep_oldest"        // earliest Modified
+	RuleKeepNewest       SelectionRuleType = "keep_newest"        // latest Modified
+	RuleKeepShortestPath SelectionRuleType = "keep_shortest_path" // fewest characters in Path
+	RuleKeepLongestPath  SelectionRuleType = "keep_longest_path"  // most characters in Path
+	RuleKeepInDirectory  SelectionRuleType = "keep_in_directory"  // Path under Value
+	RuleKeepMatching     SelectionRuleType = "keep_matching"      // Path matches regex Value
+	RuleAvoidMatching    SelectionRuleType = "avoid_matching"     // Path does not match regex Value
+)
+
+// SelectionRule is one step of an ordered rule list. Each rule narrows the
+// files eligible to be kept; later rules only break ties left by earlier ones.
+type SelectionRule struct {
+	Type  SelectionRuleType `json:"type"`
+	Value string            `json:"value,omitempty"`
+}
+
+// Selection is the proposed outcome for one group: the file to keep and the
+// files to remove, for the user to confirm before anything is deleted.
+type Selection struct {
+	GroupID string   `json:"group_id"`
+	Keep    string   `json:"keep"`
+	Remove  []string `json:"remove"`
+	Reason  string   `json:"reason"` // the rule that decided the kept file
+}
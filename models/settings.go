@@ -4,8 +4,86 @@ import (
 	"encoding/json"
 	"os"
 	"path/filepath"
+	"runtime"
+	"strings"
 )
 
+// ClusterMode selects how similar images are grouped.
+type ClusterMode string
+
+const (
+	// ClusterGreedy anchors each group on the first unused image and adds
+	// images within the threshold of it. Fast, but depends on file order.
+	ClusterGreedy ClusterMode = "greedy"
+	// ClusterConnected groups images linked by any chain of matches
+	// (A~B~C), so a group's diameter may exceed the threshold.
+	ClusterConnected ClusterMode = "connected"
+	// ClusterComplete merges groups only while every pair stays within the
+	// threshold (complete linkage).
+	ClusterComplete ClusterMode = "complete"
+)
+
+// HashAlgorithm selects the perceptual hash used to compare images.
+type HashAlgorithm string
+
+const (
+	HashAverage    HashAlgorithm = "average"    // aHash: 64 bits, fastest, weakest
+	HashDifference HashAlgorithm = "difference" // dHash: 64 bits, robust to brightness and gradient changes
+	HashPerception HashAlgorithm = "perception" // pHash: 64 bits, DCT-based, the default
+	HashExtended   HashAlgorithm = "extended"   // pHash over 16x16 coefficients: 256 bits, finer grained
+)
+
+// HashBits returns the length of hashes produced by a.
+func (a HashAlgorithm) HashBits() int {
+	if a == HashExtended {
+		return 256
+	}
+	return 64
+}
+
+// DeviceType classifies the storage behind a file for I/O scheduling.
+type DeviceType string
+
+const (
+	DeviceRotational DeviceType = "rotational" // spinning disk: seeks are expensive
+	DeviceSSD        DeviceType = "ssd"        // SATA/SAS solid state
+	DeviceNVMe       DeviceType = "nvme"       // deep hardware queues
+	DeviceUnknown    DeviceType = "unknown"    // network filesystems, or no detection on this platform
+)
+
+// IOLimits caps how many files are read at once from each device, by the
+// device's type. Zero means the default for that type.
+type IOLimits struct {
+	Rotational int `json:"rotational"`
+	SSD        int `json:"ssd"`
+	NVMe       int `json:"nvme"`
+	Unknown    int `json:"unknown"`
+}
+
+// DefaultIOLimits returns limits suited to each device type. Unknown devices
+// get one reader per CPU.
+func DefaultIOLimits() IOLimits {
+	return IOLimits{Rotational: 1, SSD: 8, NVMe: 32, Unknown: runtime.NumCPU()}
+}
+
+// Limit returns the concurrent read limit for a device of type t.
+func (l IOLimits) Limit(t DeviceType) int {
+	d := DefaultIOLimits()
+	n, def := l.Unknown, d.Unknown
+	switch t {
+	case DeviceRotational:
+		n, def = l.Rotational, d.Rotational
+	case DeviceSSD:
+		n, def = l.SSD, d.SSD
+	case DeviceNVMe:
+		n, def = l.NVMe, d.NVMe
+	}
+	if n <= 0 {
+		return def
+	}
+	return n
+}
+
 // ScanSettings holds all user-configurable scan parameters.
 // Persisted to disk so the app reopens exactly where the user left off.
 type ScanSettings struct {
@@ -15,6 +93,26 @@ type ScanSettings struct {
 	ExcludedDirs        []string `json:"excluded_dirs"`
 	SimilarityThreshold float64  `json:"similarity_threshold"`
 	SkipHidden          bool     `json:"skip_hidden"`
+	// ClusterMode selects how similar images are grouped; empty means greedy.
+	ClusterMode ClusterMode `json:"cluster_mode"`
+	// HashAlgorithm selects the perceptual hash; empty means perception.
+	// SimilarityThreshold is a distance in bits of this hash.
+	HashAlgorithm HashAlgorithm `json:"hash_algorithm"`
+	// ReferencePaths are scanned and grouped like Paths, but files under them
+	// are read-only: they are never offered for deletion or replacement.
+	ReferencePaths []string `json:"reference_paths"`
+	// MatchTransforms also hashes each image rotated by 90, 180 and 270
+	// degrees and mirrored, so rotated or flipped copies are grouped as
+	// similar. It costs roughly five times the perceptual hashing work.
+	MatchTransforms bool `json:"match_transforms"`
+	// IOLimits caps concurrent reads per storage device while hashing.
+	IOLimits IOLimits `json:"io_limits"`
+	// MaxReadRate caps the combined read rate while hashing, in bytes per
+	// second; 0 is unlimited. It can be changed while a scan runs.
+	MaxReadRate int64 `json:"max_read_rate"`
+	// IdleIOPriority reads at idle I/O priority where the platform supports
+	// it (ioprio on Linux), so scans yield the disk to other programs.
+	IdleIOPriority bool `json:"idle_io_priority"`
 }
 
 // DefaultSettings returns sensible defaults for a fresh install.
@@ -30,6 +128,10 @@ func DefaultSettings() ScanSettings {
 		},
 		SimilarityThreshold: 0,
 		SkipHidden:          true,
+		ClusterMode:         ClusterGreedy,
+		HashAlgorithm:       HashPerception,
+		ReferencePaths:      []string{},
+		IOLimits:            DefaultIOLimits(),
 	}
 }
 
@@ -67,6 +169,9 @@ func LoadSettings() ScanSettings {
 	if s.Paths == nil {
 		s.Paths = []string{}
 	}
+	if s.ReferencePaths == nil {
+		s.ReferencePaths = []string{}
+	}
 
 	return s
 }
@@ -100,3 +205,43 @@ func (s ScanSettings) MinFileSizeBytes() int64 {
 		return s.MinFileSize * 1024
 	}
 }
+
+// ScanRoots returns Paths and ReferencePaths with any root that lies inside
+// another removed, so overlapping roots are walked only once.
+func (s ScanSettings) ScanRoots() []string {
+	all := append(append([]string{}, s.Paths...), s.ReferencePaths...)
+	var roots []string
+	for i, p := range all {
+		nested := false
+		for j, q := range all {
+			if i != j && IsUnder(p, q) && (!IsUnder(q, p) || j < i) {
+				nested = true
+				break
+			}
+		}
+		if !nested {
+			roots = append(roots, p)
+		}
+	}
+	return roots
+}
+
+// IsUnder reports whether path is root or lies inside it.
+func IsUnder(path, root string) bool {
+	path, root = filepath.Clean(path), filepath.Clean(root)
+	if path == root {
+		return true
+	}
+	rel, err := filepath.Rel(root, path)
+	return err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
+
+// IsUnderAny reports whether path lies inside any of roots.
+func IsUnderAny(path string, roots []string) bool {
+	for _, r := range roots {
+		if IsUnder(path, r) {
+			return true
+		}
+	}
+	return false
+}
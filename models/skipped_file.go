@@ -0,0 +1,38 @@
+package models
+
+// SkipReason says why a scan left a file out.
+type SkipReason string
+
+const (
+	SkipPermission  SkipReason = "permission_denied" // the file or directory could not be opened
+	SkipIOError     SkipReason = "io_error"          // reading or listing it failed
+	SkipUndecodable SkipReason = "undecodable"       // an image that could not be decoded for similarity
+	SkipVanished    SkipReason = "vanished"          // deleted or moved while the scan ran
+	SkipChanged     SkipReason = "changed"           // modified during the scan, or since the checkpoint it resumed from
+)
+
+// Label returns a short human-readable description of r.
+func (r SkipReason) Label() string {
+	switch r {
+	case SkipPermission:
+		return "permission denied"
+	case SkipIOError:
+		return "I/O error"
+	case SkipUndecodable:
+		return "could not decode image"
+	case SkipVanished:
+		return "vanished during scan"
+	case SkipChanged:
+		return "changed during scan"
+	}
+	return string(r)
+}
+
+// SkippedFile is a file, or a directory, a scan could not examine, so it is
+// missing from the results.
+type SkippedFile struct {
+	Path   string     `json:"path"`
+	Reason SkipReason `json:"reason"`
+	Stage  string     `json:"stage"` // the scan stage it was skipped in
+	Error  string     `json:"error"`
+}
@@ -0,0 +1,136 @@
+package operations
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"folder-cleaner-go/models"
+
+	"github.com/google/uuid"
+)
+
+// ReplaceWithHardLinks replaces each of paths with a hard link to keep, so every
+// path keeps working while the duplicate data is freed. Content is re-verified
+// byte-for-byte first, and each swap is an atomic rename of a freshly created
+// link over the duplicate. keep and paths must be on the same filesystem.
+// It continues past individual failures and reports them in the result.
+func ReplaceWithHardLinks(keep string, paths []string) (*models.DeleteOperation, error) {
+	if len(paths) == 0 {
+		return nil, fmt.Errorf("no paths provided")
+	}
+
+	keepInfo, err := os.Stat(keep)
+	if err != nil {
+		return nil, fmt.Errorf("kept file: %w", err)
+	}
+	if !keepInfo.Mode().IsRegular() {
+		return nil, fmt.Errorf("kept file is not a regular file")
+	}
+
+	var replaced []models.ReplacedFile
+	var failed []models.FailedDelete
+
+	for _, p := range paths {
+		meta, reason := linkOne(keep, keepInfo, p)
+		if reason != "" {
+			failed = append(failed, models.FailedDelete{Path: p, Reason: reason})
+			continue
+		}
+		replaced = append(replaced, models.ReplacedFile{Path: p, Target: keep, Original: meta})
+	}
+
+	return &models.DeleteOperation{
+		ID:          uuid.New().String(),
+		Kind:        models.OpHardLink,
+		Replaced:    replaced,
+		FailedPaths: failed,
+		Timestamp:   time.Now().UTC().Format(time.RFC3339),
+	}, nil
+}
+
+// linkOne replaces path with a hard link to keep and returns the metadata path
+// had, or a failure reason.
+func linkOne(keep string, keepInfo os.FileInfo, path string) (*models.FileMeta, string) {
+	info, err := os.Lstat(path)
+	if err != nil {
+		return nil, "file not found"
+	}
+	if !info.Mode().IsRegular() {
+		return nil, "not a regular file"
+	}
+	if os.SameFile(keepInfo, info) {
+		return nil, "already linked to the kept file"
+	}
+
+	same, err := sameContent(keep, path)
+	if err != nil {
+		return nil, err.Error()
+	}
+	if !same {
+		return nil, "content differs from the kept file"
+	}
+
+	tmp := tempSibling(path)
+	if err := os.Link(keep, tmp); err != nil {
+		return nil, fmt.Sprintf("cannot link (different filesystem?): %v", err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		os.Remove(tmp)
+		return nil, err.Error()
+	}
+	return fileMeta(info), ""
+}
+
+// fileMeta returns the metadata of info that a hard link does not keep.
+func fileMeta(info os.FileInfo) *models.FileMeta {
+	uid, gid := fileOwner(info)
+	return &models.FileMeta{
+		Mode:     uint32(info.Mode() & (os.ModePerm | os.ModeSetuid | os.ModeSetgid | os.ModeSticky)),
+		Modified: info.ModTime().UnixNano(),
+		UID:      uid,
+		GID:      gid,
+	}
+}
+
+// breakHardLink gives r.Path its own copy of the data it shares with other
+// links, reverting ReplaceWithHardLinks for that path. The copy gets back the
+// mode, modification time and owner recorded in r.Original; without them it
+// takes the shared file's mode. Only root may give a file to another user, so
+// failing to restore the owner leaves the copy owned by the caller.
+func breakHardLink(r models.ReplacedFile) error {
+	info, err := os.Stat(r.Path)
+	if err != nil {
+		return err
+	}
+
+	tmp := tempSibling(r.Path)
+	if err := copyFile(r.Path, tmp, info.Mode().Perm()); err != nil {
+		return err
+	}
+	if err := restoreMeta(tmp, r.Original); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	if err := os.Rename(tmp, r.Path); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	return nil
+}
+
+// restoreMeta applies meta, if any, to the file at path.
+func restoreMeta(path string, meta *models.FileMeta) error {
+	if meta == nil {
+		return nil
+	}
+	if meta.UID >= 0 {
+		_ = os.Chown(path, meta.UID, meta.GID)
+	}
+	// After chown, which may clear setuid and setgid
+	if err := os.Chmod(path, os.FileMode(meta.Mode)); err != nil {
+		return err
+	}
+	mtime := time.Unix(0, meta.Modified)
+	return os.Chtimes(path, mtime, mtime)
+}
@@ -0,0 +1,108 @@
+package operations
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"folder-cleaner-go/models"
+)
+
+// writeFile creates a file under dir with the given content, mode and mtime.
+func writeFile(t *testing.T, dir, name, content string, mode os.FileMode, mtime time.Time) string {
+	t.Helper()
+	p := filepath.Join(dir, name)
+	if err := os.WriteFile(p, []byte(content), mode); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chmod(p, mode); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chtimes(p, mtime, mtime); err != nil {
+		t.Fatal(err)
+	}
+	return p
+}
+
+func TestHardLinkUndoRestoresMetadata(t *testing.T) {
+	dir := t.TempDir()
+	keepTime := time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)
+	dupTime := time.Date(2021, 6, 7, 8, 9, 10, 123456789, time.UTC)
+	keep := writeFile(t, dir, "keep", "same data", 0o644, keepTime)
+	dup := writeFile(t, dir, "dup", "same data", 0o600, dupTime)
+
+	op, err := ReplaceWithHardLinks(keep, []string{dup})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(op.Replaced) != 1 || len(op.FailedPaths) != 0 {
+		t.Fatalf("replaced %v, failed %v", op.Replaced, op.FailedPaths)
+	}
+	keepInfo, _ := os.Stat(keep)
+	dupInfo, _ := os.Stat(dup)
+	if !os.SameFile(keepInfo, dupInfo) {
+		t.Fatal("duplicate is not a hard link to the kept file")
+	}
+
+	result, err := Undo(*op)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(result.RestoredPaths) != 1 || len(result.FailedPaths) != 0 {
+		t.Fatalf("restored %v, failed %v", result.RestoredPaths, result.FailedPaths)
+	}
+
+	dupInfo, err = os.Stat(dup)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keepInfo, _ = os.Stat(keep)
+	if os.SameFile(keepInfo, dupInfo) {
+		t.Error("duplicate still shares the kept file's inode")
+	}
+	if got := dupInfo.Mode().Perm(); got != 0o600 {
+		t.Errorf("duplicate mode = %v, want %v", got, os.FileMode(0o600))
+	}
+	if got := dupInfo.ModTime(); !got.Equal(dupTime) {
+		t.Errorf("duplicate mtime = %v, want %v", got, dupTime)
+	}
+	if got := keepInfo.Mode().Perm(); got != 0o644 {
+		t.Errorf("kept file mode = %v, want %v", got, os.FileMode(0o644))
+	}
+	if data, _ := os.ReadFile(dup); string(data) != "same data" {
+		t.Errorf("duplicate content = %q", data)
+	}
+}
+
+func TestHardLinkUndoWithoutMetadata(t *testing.T) {
+	// Operations journaled before metadata was recorded take the shared mode
+	dir := t.TempDir()
+	now := time.Now()
+	keep := writeFile(t, dir, "keep", "data", 0o640, now)
+	dup := filepath.Join(dir, "dup")
+	if err := os.Link(keep, dup); err != nil {
+		t.Fatal(err)
+	}
+
+	op := models.DeleteOperation{
+		ID:        "op",
+		Kind:      models.OpHardLink,
+		Replaced:  []models.ReplacedFile{{Path: dup, Target: keep}},
+		Timestamp: now.UTC().Format(time.RFC3339),
+	}
+	if _, err := Undo(op); err != nil {
+		t.Fatal(err)
+	}
+	keepInfo, _ := os.Stat(keep)
+	dupInfo, err := os.Stat(dup)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if os.SameFile(keepInfo, dupInfo) {
+		t.Error("duplicate still shares the kept file's inode")
+	}
+	if got := dupInfo.Mode().Perm(); got != 0o640 {
+		t.Errorf("duplicate mode = %v, want %v", got, os.FileMode(0o640))
+	}
+}
@@ -0,0 +1,18 @@
+//go:build !windows
+
+package operations
+
+import (
+	"os"
+	"syscall"
+)
+
+// fileOwner returns the user and group owning info, or -1 for both if
+// unavailable.
+func fileOwner(info os.FileInfo) (uid, gid int) {
+	st, ok := info.Sys().(*syscall.Stat_t)
+	if !ok {
+		return -1, -1
+	}
+	return int(st.Uid), int(st.Gid)
+}
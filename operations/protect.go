@@ -0,0 +1,21 @@
+package operations
+
+import "folder-cleaner-go/models"
+
+// reasonProtected is reported for paths under a reference directory.
+const reasonProtected = "in a protected reference directory"
+
+// Unprotected splits paths into those outside every protected root and
+// failures for those inside one, so reference files are never modified.
+func Unprotected(paths, protected []string) ([]string, []models.FailedDelete) {
+	var allowed []string
+	var failed []models.FailedDelete
+	for _, p := range paths {
+		if models.IsUnderAny(p, protected) {
+			failed = append(failed, models.FailedDelete{Path: p, Reason: reasonProtected})
+			continue
+		}
+		allowed = append(allowed, p)
+	}
+	return allowed, failed
+}
@@ -0,0 +1,107 @@
+package operations
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	"folder-cleaner-go/models"
+
+	"github.com/google/uuid"
+)
+
+var (
+	// ErrDedupeUnsupported is returned where the OS has no extent-sharing
+	// ioctl; callers should fall back to links or trash.
+	ErrDedupeUnsupported = errors.New("extent deduplication is not supported on this platform")
+
+	errDedupeFS      = errors.New("filesystem does not support extent sharing")
+	errDedupeDiffers = errors.New("content differs from the kept file")
+)
+
+// DedupeFiles makes each of paths share its data extents with keep using the
+// kernel's dedupe ioctl (Btrfs, XFS), which compares the bytes itself before
+// sharing them. Every path stays an independent, writable file. The result
+// reports the free-space gain measured on keep's filesystem.
+// It continues past individual failures and reports them in the result. If
+// the filesystem refuses extent sharing before any path was deduplicated, it
+// returns ErrDedupeUnsupported, as on platforms without the ioctl.
+func DedupeFiles(keep string, paths []string) (*models.DeleteOperation, error) {
+	if !dedupeSupported {
+		return nil, ErrDedupeUnsupported
+	}
+	if len(paths) == 0 {
+		return nil, fmt.Errorf("no paths provided")
+	}
+
+	src, err := os.Open(keep)
+	if err != nil {
+		return nil, fmt.Errorf("kept file: %w", err)
+	}
+	defer src.Close()
+
+	srcInfo, err := src.Stat()
+	if err != nil {
+		return nil, fmt.Errorf("kept file: %w", err)
+	}
+
+	op := &models.DeleteOperation{
+		ID:        uuid.New().String(),
+		Kind:      models.OpDedupe,
+		Timestamp: time.Now().UTC().Format(time.RFC3339),
+	}
+
+	before, freeErr := freeBytes(keep)
+	var unsupported error
+	for _, p := range paths {
+		// Once the filesystem has refused, the rest will fail the same way
+		if unsupported != nil {
+			op.FailedPaths = append(op.FailedPaths, models.FailedDelete{Path: p, Reason: unsupported.Error()})
+			continue
+		}
+		if err := dedupeOne(src, srcInfo, p); err != nil {
+			if errors.Is(err, errDedupeFS) {
+				if len(op.Replaced) == 0 {
+					return nil, fmt.Errorf("%w: %v", ErrDedupeUnsupported, err)
+				}
+				unsupported = err
+			}
+			op.FailedPaths = append(op.FailedPaths, models.FailedDelete{Path: p, Reason: err.Error()})
+			continue
+		}
+		op.Replaced = append(op.Replaced, models.ReplacedFile{Path: p, Target: keep})
+	}
+
+	if freeErr == nil && len(op.Replaced) > 0 {
+		syncFS(src)
+		if after, err := freeBytes(keep); err == nil && after > before {
+			op.ReclaimedBytes = after - before
+		}
+	}
+
+	return op, nil
+}
+
+func dedupeOne(src *os.File, srcInfo os.FileInfo, path string) error {
+	dst, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer dst.Close()
+
+	info, err := dst.Stat()
+	if err != nil {
+		return err
+	}
+	if !info.Mode().IsRegular() {
+		return fmt.Errorf("not a regular file")
+	}
+	if os.SameFile(srcInfo, info) {
+		return fmt.Errorf("same file as the kept file")
+	}
+	if info.Size() != srcInfo.Size() {
+		return errDedupeDiffers
+	}
+	return dedupeRange(src, dst, info.Size())
+}
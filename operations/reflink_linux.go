@@ -0,0 +1,81 @@
+//go:build linux
+
+package operations
+
+import (
+	"errors"
+	"fmt"
+	"os"
+
+	"golang.org/x/sys/unix"
+)
+
+const (
+	dedupeSupported = true
+	// Filesystems cap how much one FIDEDUPERANGE call processes, so large
+	// files are submitted in chunks.
+	dedupeChunkSize = 16 * 1024 * 1024
+)
+
+// dedupeRange shares the first size bytes of dst with src via FIDEDUPERANGE.
+func dedupeRange(src, dst *os.File, size int64) error {
+	var off int64
+	for off < size {
+		length := min(size-off, dedupeChunkSize)
+		arg := unix.FileDedupeRange{
+			Src_offset: uint64(off),
+			Src_length: uint64(length),
+			Info: []unix.FileDedupeRangeInfo{{
+				Dest_fd:     int64(dst.Fd()),
+				Dest_offset: uint64(off),
+			}},
+		}
+		if err := unix.IoctlFileDedupeRange(int(src.Fd()), &arg); err != nil {
+			return dedupeError(err)
+		}
+
+		info := arg.Info[0]
+		switch {
+		case info.Status == unix.FILE_DEDUPE_RANGE_DIFFERS:
+			return errDedupeDiffers
+		case info.Status < 0:
+			return dedupeError(unix.Errno(-info.Status))
+		case info.Bytes_deduped == 0:
+			return fmt.Errorf("kernel made no progress at offset %d", off)
+		}
+		off += int64(info.Bytes_deduped)
+	}
+	return nil
+}
+
+// dedupeError maps ioctl errnos that mean "not possible here" to errDedupeFS.
+// EINVAL only concerns the file at hand, e.g. a range the filesystem will not
+// share, so the remaining files are still tried.
+func dedupeError(err error) error {
+	switch {
+	case errors.Is(err, unix.EOPNOTSUPP), errors.Is(err, unix.ENOTTY):
+		return errDedupeFS
+	case errors.Is(err, unix.EINVAL):
+		return fmt.Errorf("filesystem cannot share this file's extents: %w", err)
+	case errors.Is(err, unix.EXDEV):
+		return fmt.Errorf("file is on a different filesystem")
+	default:
+		return err
+	}
+}
+
+// freeBytes returns the space available to unprivileged users on the
+// filesystem holding path.
+func freeBytes(path string) (int64, error) {
+	var st unix.Statfs_t
+	if err := unix.Statfs(path, &st); err != nil {
+		return 0, err
+	}
+	return int64(st.Bavail) * int64(st.Bsize), nil
+}
+
+// syncFS commits the filesystem holding f so freed extents show up in statfs
+// (Btrfs otherwise reports them only after its next transaction).
+func syncFS(f *os.File) {
+	_ = unix.Syncfs(int(f.Fd()))
+}
@@ -0,0 +1,17 @@
+//go:build !linux
+
+package operations
+
+import "os"
+
+const dedupeSupported = false
+
+func dedupeRange(src, dst *os.File, size int64) error {
+	return ErrDedupeUnsupported
+}
+
+func freeBytes(path string) (int64, error) {
+	return 0, ErrDedupeUnsupported
+}
+
+func syncFS(f *os.File) {}
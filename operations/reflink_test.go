@@ -0,0 +1,106 @@
+package operations
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// skipWithoutDedupe skips tests of DedupeFiles where the ioctl is missing.
+func skipWithoutDedupe(t *testing.T) {
+	t.Helper()
+	if !dedupeSupported {
+		t.Skip("extent deduplication is not supported on this platform")
+	}
+}
+
+func TestDedupeUnsupportedPlatform(t *testing.T) {
+	if dedupeSupported {
+		t.Skip("extent deduplication is supported on this platform")
+	}
+	dir := t.TempDir()
+	keep := writeFile(t, dir, "keep", "data", 0o644, time.Now())
+	dup := writeFile(t, dir, "dup", "data", 0o644, time.Now())
+	if _, err := DedupeFiles(keep, []string{dup}); !errors.Is(err, ErrDedupeUnsupported) {
+		t.Errorf("err = %v, want %v", err, ErrDedupeUnsupported)
+	}
+}
+
+func TestDedupeSkipsUnsuitableFiles(t *testing.T) {
+	skipWithoutDedupe(t)
+	dir := t.TempDir()
+	keep := writeFile(t, dir, "keep", "same data", 0o644, time.Now())
+	shorter := writeFile(t, dir, "shorter", "same", 0o644, time.Now())
+	sub := filepath.Join(dir, "sub")
+	if err := os.Mkdir(sub, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	link := filepath.Join(dir, "link")
+	if err := os.Link(keep, link); err != nil {
+		t.Fatal(err)
+	}
+	missing := filepath.Join(dir, "missing")
+
+	// None of these reach the filesystem's extent sharing
+	op, err := DedupeFiles(keep, []string{shorter, sub, link, missing})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(op.Replaced) != 0 || op.ReclaimedBytes != 0 {
+		t.Errorf("replaced %v, reclaimed %d", op.Replaced, op.ReclaimedBytes)
+	}
+	want := map[string]string{
+		shorter: errDedupeDiffers.Error(),
+		sub:     "not a regular file",
+		link:    "same file as the kept file",
+	}
+	if len(op.FailedPaths) != 4 {
+		t.Fatalf("failed %v, want 4 paths", op.FailedPaths)
+	}
+	for _, f := range op.FailedPaths {
+		if f.Path == missing {
+			// The open error itself, naming the path, is reported
+			if !strings.Contains(f.Reason, missing) {
+				t.Errorf("%s: reason %q hides the open error", f.Path, f.Reason)
+			}
+			continue
+		}
+		if f.Reason != want[f.Path] {
+			t.Errorf("%s: reason %q, want %q", f.Path, f.Reason, want[f.Path])
+		}
+	}
+}
+
+func TestDedupeFilesystem(t *testing.T) {
+	skipWithoutDedupe(t)
+	dir := t.TempDir()
+	keep := writeFile(t, dir, "keep", "same data", 0o644, time.Now())
+	dups := []string{
+		writeFile(t, dir, "dup1", "same data", 0o644, time.Now()),
+		writeFile(t, dir, "dup2", "same data", 0o644, time.Now()),
+	}
+
+	op, err := DedupeFiles(keep, dups)
+	if errors.Is(err, ErrDedupeUnsupported) {
+		// ext4, tmpfs and the like: nothing is recorded or touched
+		if op != nil {
+			t.Errorf("unsupported filesystem returned operation %+v", op)
+		}
+		for _, p := range dups {
+			assertOriginal(t, p, "same data")
+		}
+		return
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(op.Replaced) != 2 || len(op.FailedPaths) != 0 {
+		t.Fatalf("replaced %v, failed %v", op.Replaced, op.FailedPaths)
+	}
+	for _, p := range dups {
+		assertOriginal(t, p, "same data")
+	}
+}
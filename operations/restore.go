@@ -0,0 +1,150 @@
+package operations
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"folder-cleaner-go/models"
+
+	"github.com/Bios-Marcel/wastebasket/v2"
+)
+
+// trashLocation is implemented by trash entries whose storage location is
+// known (the freedesktop trash on Linux), which lets us restore them under a
+// different name when the original path has been reused.
+type trashLocation interface {
+	CurrentPath() string
+	InfoPath() string
+}
+
+// restoreFromTrash moves each of paths back from the system trash to where it
+// was trashed from, between from and to, appending the outcome to result. When a path is now taken
+// by another file, the original is restored beside it as "name (restored).ext".
+// If prepare is not nil, it is called for each path once its trash entry has
+// been found, just before restoring it; an error leaves that path in the trash.
+func restoreFromTrash(paths []string, from, to time.Time, result *models.UndoResult, prepare func(path string) error) {
+	if len(paths) == 0 {
+		return
+	}
+
+	found, err := wastebasket.Query(wastebasket.QueryOptions{Search: paths})
+	if err != nil {
+		reason := err.Error()
+		if errors.Is(err, wastebasket.ErrPlatformNotSupported) {
+			reason = "restoring from the trash is not supported on this platform; use the file manager's Put Back"
+		}
+		for _, p := range paths {
+			result.FailedPaths = append(result.FailedPaths, models.FailedDelete{Path: p, Reason: reason})
+		}
+		return
+	}
+
+	for _, p := range paths {
+		entry := closestTrashEntry(found.Matches[p], from, to)
+		if entry == nil {
+			result.FailedPaths = append(result.FailedPaths, models.FailedDelete{Path: p, Reason: "not found in trash"})
+			continue
+		}
+		if prepare != nil {
+			if err := prepare(p); err != nil {
+				result.FailedPaths = append(result.FailedPaths, models.FailedDelete{Path: p, Reason: err.Error()})
+				continue
+			}
+		}
+
+		restoredAs, err := restoreEntry(entry, p)
+		if err != nil {
+			result.FailedPaths = append(result.FailedPaths, models.FailedDelete{Path: p, Reason: err.Error()})
+			continue
+		}
+		result.RestoredPaths = append(result.RestoredPaths, restoredAs)
+		if restoredAs != p {
+			if result.Renamed == nil {
+				result.Renamed = make(map[string]string)
+			}
+			result.Renamed[p] = restoredAs
+		}
+	}
+}
+
+// restoreEntry restores a single trash entry, returning the path it now has.
+func restoreEntry(entry wastebasket.TrashedFileInfo, path string) (string, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return "", err
+	}
+
+	if _, err := os.Lstat(path); errors.Is(err, os.ErrNotExist) {
+		if err := entry.Restore(false); err != nil {
+			return "", err
+		}
+		return path, nil
+	}
+
+	loc, ok := entry.(trashLocation)
+	if !ok {
+		return "", fmt.Errorf("a file already exists at the original location")
+	}
+	alt := restoredName(path)
+	if err := os.Rename(loc.CurrentPath(), alt); err != nil {
+		return "", err
+	}
+	_ = os.Remove(loc.InfoPath())
+	return alt, nil
+}
+
+// restoredName returns an unused sibling name for path.
+func restoredName(path string) string {
+	ext := filepath.Ext(path)
+	base := strings.TrimSuffix(path, ext)
+	candidate := base + " (restored)" + ext
+	for i := 2; ; i++ {
+		if _, err := os.Lstat(candidate); errors.Is(err, os.ErrNotExist) {
+			return candidate
+		}
+		candidate = fmt.Sprintf("%s (restored %d)%s", base, i, ext)
+	}
+}
+
+// trashTimeTolerance is how far outside an operation's time span a trash
+// entry's deletion date may be. Anything further away was trashed by
+// something else, e.g. an older file that had the same path.
+const trashTimeTolerance = time.Minute
+
+// closestTrashEntry picks the entry trashed nearest to the span from..to,
+// since the same path may have been trashed more than once, or nil if none
+// was trashed within trashTimeTolerance of it.
+func closestTrashEntry(entries []wastebasket.TrashedFileInfo, from, to time.Time) wastebasket.TrashedFileInfo {
+	// Freedesktop .trashinfo dates are local wall-clock times without a zone,
+	// which wastebasket parses as UTC; compare against that reading as well.
+	const layout = "2006-01-02T15:04:05"
+	wallFrom, _ := time.Parse(layout, from.Local().Format(layout))
+	wallTo, _ := time.Parse(layout, to.Local().Format(layout))
+
+	var best wastebasket.TrashedFileInfo
+	var bestDist time.Duration
+	for _, e := range entries {
+		d := min(distanceTo(e.DeletionDate(), from, to), distanceTo(e.DeletionDate(), wallFrom, wallTo))
+		if d > trashTimeTolerance {
+			continue
+		}
+		if best == nil || d < bestDist {
+			best, bestDist = e, d
+		}
+	}
+	return best
+}
+
+// distanceTo returns how far t is from the span from..to; 0 within it.
+func distanceTo(t, from, to time.Time) time.Duration {
+	switch {
+	case t.Before(from):
+		return from.Sub(t)
+	case t.After(to):
+		return t.Sub(to)
+	}
+	return 0
+}
@@ -0,0 +1,177 @@
+package operations
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"folder-cleaner-go/models"
+
+	"github.com/Bios-Marcel/wastebasket/v2"
+	"github.com/google/uuid"
+)
+
+// ReplaceWithSymlinks replaces each of paths with a symbolic link to keep.
+// Unlike hard links this works across filesystems. Each duplicate is
+// re-verified byte-for-byte against keep before it is touched. Originals are
+// moved to the system trash, or under opts.BackupDir if set.
+// It continues past individual failures and reports them in the result.
+func ReplaceWithSymlinks(keep string, paths []string, opts models.SymlinkOptions) (*models.DeleteOperation, error) {
+	if len(paths) == 0 {
+		return nil, fmt.Errorf("no paths provided")
+	}
+
+	keep, err := filepath.Abs(keep)
+	if err != nil {
+		return nil, err
+	}
+	keepInfo, err := os.Stat(keep)
+	if err != nil {
+		return nil, fmt.Errorf("kept file: %w", err)
+	}
+	if !keepInfo.Mode().IsRegular() {
+		return nil, fmt.Errorf("kept file is not a regular file")
+	}
+
+	op := &models.DeleteOperation{
+		ID:      uuid.New().String(),
+		Kind:    models.OpSymlink,
+		Started: time.Now().UTC().Format(time.RFC3339),
+	}
+
+	for _, p := range paths {
+		r, reason := symlinkOne(keep, p, op.ID, opts)
+		if reason != "" {
+			op.FailedPaths = append(op.FailedPaths, models.FailedDelete{Path: p, Reason: reason})
+		}
+		// A failed replacement still returns its record if the original
+		// could not be put back, so undo can find it
+		if r.Path != "" {
+			op.Replaced = append(op.Replaced, r)
+		}
+	}
+
+	op.Timestamp = time.Now().UTC().Format(time.RFC3339)
+	return op, nil
+}
+
+// symlinkOne replaces path with a link to keep and returns the record of the
+// replacement, or a failure reason. If the link cannot be placed after the
+// original was preserved, the original is put back; should that fail too, the
+// record is returned along with the reason, as the path is now missing.
+func symlinkOne(keep, path, opID string, opts models.SymlinkOptions) (models.ReplacedFile, string) {
+	path, err := filepath.Abs(path)
+	if err != nil {
+		return models.ReplacedFile{}, err.Error()
+	}
+	info, err := os.Lstat(path)
+	if err != nil {
+		return models.ReplacedFile{}, "file not found"
+	}
+	if !info.Mode().IsRegular() {
+		return models.ReplacedFile{}, "not a regular file"
+	}
+	same, err := sameContent(keep, path)
+	if err != nil {
+		return models.ReplacedFile{}, err.Error()
+	}
+	if !same {
+		return models.ReplacedFile{}, "content differs from the kept file"
+	}
+
+	target := keep
+	if opts.Relative {
+		if target, err = filepath.Rel(filepath.Dir(path), keep); err != nil {
+			return models.ReplacedFile{}, err.Error()
+		}
+	}
+
+	// Create the link beside the duplicate first, so a failure here leaves
+	// the original untouched.
+	tmp := tempSibling(path)
+	if err := os.Symlink(target, tmp); err != nil {
+		return models.ReplacedFile{}, fmt.Sprintf("cannot create symlink: %v", err)
+	}
+
+	r := models.ReplacedFile{Path: path, Target: target}
+	trashedAt := time.Now()
+	if opts.BackupDir != "" {
+		r.BackupPath = backupPath(opts.BackupDir, opID, path)
+		err = moveFile(path, r.BackupPath)
+	} else {
+		err = wastebasket.Trash(path)
+	}
+	if err != nil {
+		os.Remove(tmp)
+		return models.ReplacedFile{}, fmt.Sprintf("cannot preserve original: %v", err)
+	}
+
+	if err := os.Rename(tmp, path); err != nil {
+		os.Remove(tmp)
+		back, perr := putBack(r, trashedAt)
+		if perr != nil {
+			return r, fmt.Sprintf("link not placed: %v; original could not be put back (%v) and can be restored by undo", err, perr)
+		}
+		return models.ReplacedFile{}, fmt.Sprintf("link not placed, original put back at %s: %v", back, err)
+	}
+	return r, ""
+}
+
+// putBack returns the original preserved for r to its path, from the backup
+// directory or the trash, and reports where it now is: beside the path if
+// the trash restore found it taken.
+func putBack(r models.ReplacedFile, trashedAt time.Time) (string, error) {
+	if r.BackupPath != "" {
+		return r.Path, moveFile(r.BackupPath, r.Path)
+	}
+	var res models.UndoResult
+	restoreFromTrash([]string{r.Path}, trashedAt, trashedAt, &res, nil)
+	if len(res.FailedPaths) > 0 {
+		return "", fmt.Errorf("%s", res.FailedPaths[0].Reason)
+	}
+	return res.RestoredPaths[0], nil
+}
+
+// backupPath mirrors path under dir/opID so originals from different
+// directories with the same name never collide.
+func backupPath(dir, opID, path string) string {
+	vol := filepath.VolumeName(path)
+	rest := strings.TrimLeft(strings.TrimPrefix(path, vol), `/\`)
+	vol = strings.Trim(strings.ReplaceAll(vol, ":", ""), `/\`)
+	return filepath.Join(dir, opID, vol, rest)
+}
+
+// moveFile renames src to dst, creating dst's directory, and falls back to
+// copy-and-delete when they are on different filesystems.
+func moveFile(src, dst string) error {
+	if err := os.MkdirAll(filepath.Dir(dst), 0o755); err != nil {
+		return err
+	}
+	if err := os.Rename(src, dst); err == nil {
+		return nil
+	}
+
+	info, err := os.Stat(src)
+	if err != nil {
+		return err
+	}
+	if err := copyFile(src, dst, info.Mode().Perm()); err != nil {
+		return err
+	}
+	_ = os.Chtimes(dst, info.ModTime(), info.ModTime())
+	return os.Remove(src)
+}
+
+// restoreBackup puts the original kept under BackupPath back in place of
+// the symlink created by ReplaceWithSymlinks.
+func restoreBackup(r models.ReplacedFile) error {
+	if _, err := os.Stat(r.BackupPath); err != nil {
+		return fmt.Errorf("backup missing: %w", err)
+	}
+	if err := removeLink(r.Path); err != nil {
+		return err
+	}
+	return moveFile(r.BackupPath, r.Path)
+}
@@ -0,0 +1,269 @@
+package operations
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+	"time"
+
+	"folder-cleaner-go/models"
+
+	"github.com/Bios-Marcel/wastebasket/v2"
+)
+
+// TestMain points the freedesktop trash at a temporary directory, as the
+// trash location is looked up once per process. It shares the filesystem of
+// t.TempDir, so trashed files are moved there rather than to a .Trash-uid
+// directory beside them.
+func TestMain(m *testing.M) {
+	dir, err := os.MkdirTemp("", "shadowwipe-trash-")
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	os.Setenv("XDG_DATA_HOME", dir)
+	code := m.Run()
+	os.RemoveAll(dir)
+	os.Exit(code)
+}
+
+// skipWithoutTrash skips tests that restore from the trash where it cannot
+// be queried.
+func skipWithoutTrash(t *testing.T) {
+	t.Helper()
+	if runtime.GOOS != "linux" {
+		t.Skip("restoring from the trash is only supported on Linux")
+	}
+}
+
+// duplicates creates keep and two copies of it in separate directories under
+// dir, returning their paths.
+func duplicates(t *testing.T, dir string) (keep string, dups []string) {
+	t.Helper()
+	mtime := time.Now().Add(-time.Hour)
+	for _, sub := range []string{"keep", "a", "b"} {
+		if err := os.Mkdir(filepath.Join(dir, sub), 0o755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	keep = writeFile(t, filepath.Join(dir, "keep"), "photo.jpg", "same bytes", 0o644, mtime)
+	dups = []string{
+		writeFile(t, filepath.Join(dir, "a"), "photo.jpg", "same bytes", 0o600, mtime),
+		writeFile(t, filepath.Join(dir, "b"), "copy.jpg", "same bytes", 0o644, mtime),
+	}
+	return keep, dups
+}
+
+// assertLinked checks that path is a symlink resolving to keep.
+func assertLinked(t *testing.T, path, keep string) {
+	t.Helper()
+	info, err := os.Lstat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Mode()&os.ModeSymlink == 0 {
+		t.Fatalf("%s is not a symlink", path)
+	}
+	resolved, err := filepath.EvalSymlinks(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want, _ := filepath.EvalSymlinks(keep)
+	if resolved != want {
+		t.Errorf("%s resolves to %s, want %s", path, resolved, want)
+	}
+}
+
+// assertOriginal checks that path is a regular file holding content.
+func assertOriginal(t *testing.T, path, content string) {
+	t.Helper()
+	info, err := os.Lstat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !info.Mode().IsRegular() {
+		t.Fatalf("%s is not a regular file", path)
+	}
+	if data, _ := os.ReadFile(path); string(data) != content {
+		t.Errorf("%s = %q, want %q", path, data, content)
+	}
+}
+
+func TestSymlinkBackupAndUndo(t *testing.T) {
+	dir := t.TempDir()
+	keep, dups := duplicates(t, dir)
+	backupDir := filepath.Join(dir, "backup")
+	different := writeFile(t, filepath.Join(dir, "a"), "other.jpg", "other bytes", 0o644, time.Now())
+
+	op, err := ReplaceWithSymlinks(keep, append(dups, different), models.SymlinkOptions{Relative: true, BackupDir: backupDir})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(op.Replaced) != 2 || len(op.FailedPaths) != 1 || op.FailedPaths[0].Path != different {
+		t.Fatalf("replaced %v, failed %v", op.Replaced, op.FailedPaths)
+	}
+	assertOriginal(t, different, "other bytes")
+	for i, r := range op.Replaced {
+		assertLinked(t, dups[i], keep)
+		if filepath.IsAbs(r.Target) {
+			t.Errorf("relative link target %q is absolute", r.Target)
+		}
+		if r.BackupPath == "" || !models.IsUnder(r.BackupPath, filepath.Join(backupDir, op.ID)) {
+			t.Errorf("backup %q is not under the operation's backup directory", r.BackupPath)
+		}
+		assertOriginal(t, r.BackupPath, "same bytes")
+	}
+
+	result, err := Undo(*op)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(result.RestoredPaths) != 2 || len(result.FailedPaths) != 0 {
+		t.Fatalf("restored %v, failed %v", result.RestoredPaths, result.FailedPaths)
+	}
+	for i, r := range op.Replaced {
+		assertOriginal(t, dups[i], "same bytes")
+		if _, err := os.Lstat(r.BackupPath); !os.IsNotExist(err) {
+			t.Errorf("backup %s left behind", r.BackupPath)
+		}
+	}
+	if info, _ := os.Stat(dups[0]); info.Mode().Perm() != 0o600 {
+		t.Errorf("restored mode = %v, want %v", info.Mode().Perm(), os.FileMode(0o600))
+	}
+}
+
+func TestSymlinkUndoKeepsReplacedLink(t *testing.T) {
+	// A link that has since been replaced by a real file is left alone
+	dir := t.TempDir()
+	keep, dups := duplicates(t, dir)
+	op, err := ReplaceWithSymlinks(keep, dups[:1], models.SymlinkOptions{BackupDir: filepath.Join(dir, "backup")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Remove(dups[0]); err != nil {
+		t.Fatal(err)
+	}
+	writeFile(t, filepath.Dir(dups[0]), filepath.Base(dups[0]), "edited", 0o644, time.Now())
+
+	result, err := Undo(*op)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(result.RestoredPaths) != 0 || len(result.FailedPaths) != 1 {
+		t.Fatalf("restored %v, failed %v", result.RestoredPaths, result.FailedPaths)
+	}
+	assertOriginal(t, dups[0], "edited")
+	assertOriginal(t, op.Replaced[0].BackupPath, "same bytes")
+}
+
+func TestSymlinkTrashAndUndo(t *testing.T) {
+	skipWithoutTrash(t)
+	dir := t.TempDir()
+	keep, dups := duplicates(t, dir)
+
+	op, err := ReplaceWithSymlinks(keep, dups, models.SymlinkOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(op.Replaced) != 2 || len(op.FailedPaths) != 0 {
+		t.Fatalf("replaced %v, failed %v", op.Replaced, op.FailedPaths)
+	}
+	for i, r := range op.Replaced {
+		assertLinked(t, dups[i], keep)
+		if r.BackupPath != "" || r.Target != keep {
+			t.Errorf("replaced %+v, want an absolute link and no backup", r)
+		}
+	}
+
+	result, err := Undo(*op)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(result.RestoredPaths) != 2 || len(result.FailedPaths) != 0 {
+		t.Fatalf("restored %v, failed %v", result.RestoredPaths, result.FailedPaths)
+	}
+	for _, p := range dups {
+		assertOriginal(t, p, "same bytes")
+	}
+}
+
+func TestSymlinkKeptFileChanged(t *testing.T) {
+	dir := t.TempDir()
+	keep, dups := duplicates(t, dir)
+	writeFile(t, filepath.Dir(keep), filepath.Base(keep), "edited bytes", 0o644, time.Now())
+
+	op, err := ReplaceWithSymlinks(keep, dups, models.SymlinkOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(op.Replaced) != 0 || len(op.FailedPaths) != 2 {
+		t.Fatalf("replaced %v, failed %v", op.Replaced, op.FailedPaths)
+	}
+	for _, f := range op.FailedPaths {
+		if f.Reason != "content differs from the kept file" {
+			t.Errorf("%s: reason %q", f.Path, f.Reason)
+		}
+	}
+	for _, p := range dups {
+		assertOriginal(t, p, "same bytes")
+	}
+}
+
+func TestPutBackFromBackup(t *testing.T) {
+	dir := t.TempDir()
+	path := writeFile(t, dir, "photo.jpg", "original", 0o644, time.Now())
+	r := models.ReplacedFile{Path: path, BackupPath: backupPath(filepath.Join(dir, "backup"), "op", path)}
+	if err := moveFile(path, r.BackupPath); err != nil {
+		t.Fatal(err)
+	}
+
+	back, err := putBack(r, time.Now())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if back != path {
+		t.Errorf("put back at %s, want %s", back, path)
+	}
+	assertOriginal(t, path, "original")
+	if _, err := os.Lstat(r.BackupPath); !os.IsNotExist(err) {
+		t.Errorf("backup %s left behind", r.BackupPath)
+	}
+}
+
+func TestPutBackFromTrash(t *testing.T) {
+	skipWithoutTrash(t)
+	dir := t.TempDir()
+	path := writeFile(t, dir, "photo.jpg", "original", 0o644, time.Now())
+	taken := writeFile(t, dir, "taken.jpg", "taken original", 0o644, time.Now())
+	trashedAt := time.Now()
+	if err := wastebasket.Trash(path, taken); err != nil {
+		t.Fatal(err)
+	}
+
+	back, err := putBack(models.ReplacedFile{Path: path}, trashedAt)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if back != path {
+		t.Errorf("put back at %s, want %s", back, path)
+	}
+	assertOriginal(t, path, "original")
+
+	// Something now occupies the path, so the original goes beside it
+	writeFile(t, dir, "taken.jpg", "newcomer", 0o644, time.Now())
+	back, err = putBack(models.ReplacedFile{Path: taken}, trashedAt)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := filepath.Join(dir, "taken (restored).jpg"); back != want {
+		t.Errorf("put back at %s, want %s", back, want)
+	}
+	assertOriginal(t, back, "taken original")
+	assertOriginal(t, taken, "newcomer")
+
+	if _, err := putBack(models.ReplacedFile{Path: filepath.Join(dir, "never-trashed.jpg")}, trashedAt); err == nil {
+		t.Error("put back a file that was never trashed")
+	}
+}
@@ -11,15 +11,17 @@ import (
 	"github.com/google/uuid"
 )
 
-// MoveToTrash safely moves the specified files to the system trash.
+// MoveToTrash safely moves the specified files to the system trash. Paths
+// under any of the protected reference roots are refused.
 // It continues past individual failures and reports them in the result.
-func MoveToTrash(paths []string) (*models.DeleteOperation, error) {
+func MoveToTrash(paths, protected []string) (*models.DeleteOperation, error) {
 	if len(paths) == 0 {
 		return nil, fmt.Errorf("no paths provided")
 	}
 
 	var deleted []string
-	var failed []models.FailedDelete
+	paths, failed := Unprotected(paths, protected)
+	started := time.Now()
 
 	for _, p := range paths {
 		if _, err := os.Stat(p); err != nil {
@@ -35,8 +37,10 @@ func MoveToTrash(paths []string) (*models.DeleteOperation, error) {
 
 	return &models.DeleteOperation{
 		ID:           uuid.New().String(),
+		Kind:         models.OpTrash,
 		DeletedPaths: deleted,
 		FailedPaths:  failed,
 		Timestamp:    time.Now().UTC().Format(time.RFC3339),
+		Started:      started.UTC().Format(time.RFC3339),
 	}, nil
 }
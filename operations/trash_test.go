@@ -0,0 +1,198 @@
+package operations
+
+import (
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestTrashUndo(t *testing.T) {
+	skipWithoutTrash(t)
+	dir := t.TempDir()
+	mtime := time.Now().Add(-time.Hour).Truncate(time.Second)
+	a := writeFile(t, dir, "a.txt", "first", 0o644, mtime)
+	b := writeFile(t, dir, "b.txt", "second", 0o644, mtime)
+	ref := writeFile(t, dir, "ref.txt", "reference", 0o644, mtime)
+
+	op, err := MoveToTrash([]string{a, b, ref}, []string{ref})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(op.DeletedPaths) != 2 || len(op.FailedPaths) != 1 || op.FailedPaths[0].Path != ref {
+		t.Fatalf("deleted %v, failed %v", op.DeletedPaths, op.FailedPaths)
+	}
+	if _, err := os.Stat(ref); err != nil {
+		t.Errorf("protected file was touched: %v", err)
+	}
+	for _, p := range op.DeletedPaths {
+		if _, err := os.Lstat(p); !os.IsNotExist(err) {
+			t.Fatalf("%s still exists after trashing", p)
+		}
+	}
+
+	// b's name has been reused since, so it comes back beside the new file
+	writeFile(t, dir, "b.txt", "new", 0o644, mtime)
+
+	result, err := Undo(*op)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(result.RestoredPaths) != 2 || len(result.FailedPaths) != 0 {
+		t.Fatalf("restored %v, failed %v", result.RestoredPaths, result.FailedPaths)
+	}
+	want := map[string]string{a: "first", filepath.Join(dir, "b (restored).txt"): "second", b: "new"}
+	for p, content := range want {
+		if data, err := os.ReadFile(p); err != nil || string(data) != content {
+			t.Errorf("%s = %q, %v; want %q", p, data, err, content)
+		}
+	}
+	if got := result.Renamed[b]; got != filepath.Join(dir, "b (restored).txt") {
+		t.Errorf("Renamed[%s] = %q", b, got)
+	}
+	if orig := result.Originals(); orig[0] != a || orig[1] != b {
+		t.Errorf("Originals() = %v, want [%s %s]", orig, a, b)
+	}
+}
+
+func TestTrashUndoSkipsUndonePaths(t *testing.T) {
+	skipWithoutTrash(t)
+	dir := t.TempDir()
+	a := writeFile(t, dir, "a.txt", "a", 0o644, time.Now())
+	b := writeFile(t, dir, "b.txt", "b", 0o644, time.Now())
+
+	op, err := MoveToTrash([]string{a, b}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// a was restored by an earlier, partial undo; it stays in the trash here
+	op.UndonePaths = []string{a}
+	result, err := Undo(*op)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(result.RestoredPaths) != 1 || result.RestoredPaths[0] != b {
+		t.Errorf("restored %v, want [%s]", result.RestoredPaths, b)
+	}
+	if _, err := os.Lstat(a); !os.IsNotExist(err) {
+		t.Errorf("%s was restored again", a)
+	}
+
+	op.MarkUndone(result.Originals())
+	if !op.Undone {
+		t.Error("operation not marked undone once every path was restored")
+	}
+	if _, err := Undo(*op); err == nil {
+		t.Error("undoing an undone operation succeeded")
+	}
+}
+
+// backdateTrash moves the deletion date of every trash entry for path back by
+// d, as if it had been trashed that much earlier.
+func backdateTrash(t *testing.T, path string, d time.Duration) {
+	t.Helper()
+	const layout = "2006-01-02T15:04:05"
+	infos, err := filepath.Glob(filepath.Join(os.Getenv("XDG_DATA_HOME"), "Trash", "info", "*.trashinfo"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	found := false
+	for _, info := range infos {
+		data, err := os.ReadFile(info)
+		if err != nil {
+			t.Fatal(err)
+		}
+		lines := strings.Split(string(data), "\n")
+		var orig string
+		for _, l := range lines {
+			if v, ok := strings.CutPrefix(l, "Path="); ok {
+				orig, _ = url.PathUnescape(v)
+			}
+		}
+		if orig != path {
+			continue
+		}
+		for i, l := range lines {
+			if v, ok := strings.CutPrefix(l, "DeletionDate="); ok {
+				at, err := time.ParseInLocation(layout, v, time.Local)
+				if err != nil {
+					t.Fatal(err)
+				}
+				lines[i] = "DeletionDate=" + at.Add(-d).Format(layout)
+			}
+		}
+		if err := os.WriteFile(info, []byte(strings.Join(lines, "\n")), 0o600); err != nil {
+			t.Fatal(err)
+		}
+		found = true
+	}
+	if !found {
+		t.Fatalf("no trash entry for %s", path)
+	}
+}
+
+func TestTrashUndoPicksEntryByTime(t *testing.T) {
+	skipWithoutTrash(t)
+	dir := t.TempDir()
+	path := writeFile(t, dir, "notes.txt", "old", 0o644, time.Now())
+	older, err := MoveToTrash([]string{path}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	backdateTrash(t, path, 3*time.Hour)
+	older.Started = time.Now().Add(-3 * time.Hour).UTC().Format(time.RFC3339)
+	older.Timestamp = older.Started
+
+	writeFile(t, dir, "notes.txt", "new", 0o644, time.Now())
+	newer, err := MoveToTrash([]string{path}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	result, err := Undo(*newer)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(result.RestoredPaths) != 1 || len(result.FailedPaths) != 0 {
+		t.Fatalf("restored %v, failed %v", result.RestoredPaths, result.FailedPaths)
+	}
+	assertOriginal(t, path, "new")
+	if err := os.Remove(path); err != nil {
+		t.Fatal(err)
+	}
+
+	result, err = Undo(*older)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(result.RestoredPaths) != 1 || len(result.FailedPaths) != 0 {
+		t.Fatalf("restored %v, failed %v", result.RestoredPaths, result.FailedPaths)
+	}
+	assertOriginal(t, path, "old")
+}
+
+func TestTrashUndoIgnoresUnrelatedEntries(t *testing.T) {
+	// The operation's own entry was purged, leaving only one trashed hours
+	// before it from the same path
+	skipWithoutTrash(t)
+	dir := t.TempDir()
+	path := writeFile(t, dir, "notes.txt", "unrelated", 0o644, time.Now())
+	op, err := MoveToTrash([]string{path}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	backdateTrash(t, path, 3*time.Hour)
+
+	result, err := Undo(*op)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(result.RestoredPaths) != 0 || len(result.FailedPaths) != 1 || result.FailedPaths[0].Reason != "not found in trash" {
+		t.Fatalf("restored %v, failed %v", result.RestoredPaths, result.FailedPaths)
+	}
+	if _, err := os.Lstat(path); !os.IsNotExist(err) {
+		t.Errorf("unrelated file restored to %s", path)
+	}
+}
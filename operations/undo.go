@@ -0,0 +1,99 @@
+package operations
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"folder-cleaner-go/models"
+)
+
+// Undo reverts op, continuing past individual failures and reporting them
+// in the result. Paths restored by an earlier undo (op.UndonePaths) are
+// skipped.
+func Undo(op models.DeleteOperation) (*models.UndoResult, error) {
+	if op.Undone {
+		return nil, fmt.Errorf("operation already undone")
+	}
+
+	trashedTo, err := time.Parse(time.RFC3339, op.Timestamp)
+	if err != nil {
+		return nil, fmt.Errorf("invalid operation timestamp: %w", err)
+	}
+	// Operations recorded before Started existed only have one time
+	trashedFrom := trashedTo
+	if op.Started != "" {
+		if trashedFrom, err = time.Parse(time.RFC3339, op.Started); err != nil {
+			return nil, fmt.Errorf("invalid operation start time: %w", err)
+		}
+	}
+
+	result := &models.UndoResult{OperationID: op.ID}
+	done := make(map[string]bool, len(op.UndonePaths))
+	for _, p := range op.UndonePaths {
+		done[p] = true
+	}
+	var deleted []string
+	for _, p := range op.DeletedPaths {
+		if !done[p] {
+			deleted = append(deleted, p)
+		}
+	}
+	var replaced []models.ReplacedFile
+	for _, r := range op.Replaced {
+		if !done[r.Path] {
+			replaced = append(replaced, r)
+		}
+	}
+
+	switch op.Kind {
+	case models.OpTrash, "": // operations recorded before kinds existed were trashes
+		restoreFromTrash(deleted, trashedFrom, trashedTo, result, nil)
+	case models.OpHardLink:
+		for _, r := range replaced {
+			if err := breakHardLink(r); err != nil {
+				result.FailedPaths = append(result.FailedPaths, models.FailedDelete{Path: r.Path, Reason: err.Error()})
+				continue
+			}
+			result.RestoredPaths = append(result.RestoredPaths, r.Path)
+		}
+	case models.OpSymlink:
+		var fromTrash []string
+		for _, r := range replaced {
+			if r.BackupPath == "" {
+				fromTrash = append(fromTrash, r.Path)
+				continue
+			}
+			if err := restoreBackup(r); err != nil {
+				result.FailedPaths = append(result.FailedPaths, models.FailedDelete{Path: r.Path, Reason: err.Error()})
+				continue
+			}
+			result.RestoredPaths = append(result.RestoredPaths, r.Path)
+		}
+		// Each link is cleared so the original can take its place, but only
+		// once the original has been found in the trash
+		restoreFromTrash(fromTrash, trashedFrom, trashedTo, result, removeLink)
+	case models.OpDedupe:
+		return nil, fmt.Errorf("deduplicated files are already independent copies; nothing to undo")
+	default:
+		return nil, fmt.Errorf("undo is not supported for %q operations", op.Kind)
+	}
+
+	return result, nil
+}
+
+// removeLink deletes the symlink at path, refusing to touch anything that has
+// since been replaced by a regular file.
+func removeLink(path string) error {
+	info, err := os.Lstat(path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if info.Mode()&os.ModeSymlink == 0 {
+		return fmt.Errorf("path is no longer a symlink")
+	}
+	return os.Remove(path)
+}
@@ -0,0 +1,276 @@
+package scanner
+
+import (
+	"encoding/gob"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"folder-cleaner-go/models"
+)
+
+const (
+	// cacheVersion 2: perceptual hashes are taken after EXIF orientation.
+	// cacheVersion 3: mtimes are kept in nanoseconds.
+	cacheVersion = 3
+	// cacheMaxAge evicts entries for files that have not been seen by any
+	// scan for this long (deleted files, unplugged drives, old scan roots).
+	cacheMaxAge = 90 * 24 * time.Hour
+)
+
+// hashKind selects which hash a cache lookup or store refers to.
+type hashKind int
+
+const (
+	hashPartial hashKind = iota
+	hashFull
+	hashPerceptual
+)
+
+// cacheEntry is the persisted record for one path. Hashes are only trusted
+// while size, mtime and inode still match the file on disk.
+type cacheEntry struct {
+	Size        int64
+	Modified    int64 // Unix nanoseconds
+	Inode       uint64
+	PartialHash string
+	FullHash    string
+	// Perceptual holds one tagged perceptual hash per algorithm, so changing
+	// the algorithm does not discard hashes computed with another. A hash may
+	// include the variants for transform matching.
+	Perceptual map[models.HashAlgorithm]string
+	Seen       int64 // Unix time of the last scan that touched this entry
+}
+
+type cacheFile struct {
+	Version int
+	Entries map[string]cacheEntry
+}
+
+// HashCache is a persistent path-keyed store of previously computed hashes,
+// letting rescans skip files that have not changed. A nil *HashCache is valid
+// and behaves as an always-empty cache.
+type HashCache struct {
+	mu      sync.Mutex
+	path    string
+	entries map[string]cacheEntry
+	dirty   bool
+}
+
+// DefaultHashCachePath returns the location of the shared hash cache file.
+func DefaultHashCachePath() (string, error) {
+	cacheDir, err := os.UserCacheDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(cacheDir, "ShadowWipe", "hashes.gob"), nil
+}
+
+// OpenHashCache loads the cache at path. A missing, corrupt or outdated file
+// yields an empty cache rather than an error, since it is only an optimisation.
+func OpenHashCache(path string) *HashCache {
+	c := &HashCache{path: path, entries: make(map[string]cacheEntry)}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return c
+	}
+	defer f.Close()
+
+	var data cacheFile
+	if err := gob.NewDecoder(f).Decode(&data); err != nil || data.Version != cacheVersion {
+		return c
+	}
+	if data.Entries != nil {
+		c.entries = data.Entries
+	}
+	return c
+}
+
+// lookup returns the cached entry for f if it is still valid. Stale entries
+// are dropped so they are not written back.
+func (c *HashCache) lookup(f models.FileInfo, now int64) (cacheEntry, bool) {
+	e, ok := c.entries[f.Path]
+	if !ok {
+		return cacheEntry{}, false
+	}
+	if e.Size != f.Size || e.Modified != f.ModifiedNano || e.Inode != f.Inode {
+		delete(c.entries, f.Path)
+		c.dirty = true
+		return cacheEntry{}, false
+	}
+	if e.Seen != now {
+		e.Seen = now
+		c.entries[f.Path] = e
+		c.dirty = true
+	}
+	return e, true
+}
+
+// get returns f's hash of the given kind from a valid cache entry, or "" if
+// there is none. algo selects which perceptual hash to use, and transforms
+// whether it must include the rotated and mirrored variants; both are
+// ignored for other kinds.
+func (c *HashCache) get(f models.FileInfo, kind hashKind, algo models.HashAlgorithm, transforms bool) string {
+	if c == nil {
+		return ""
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	e, ok := c.lookup(f, time.Now().Unix())
+	if !ok {
+		return ""
+	}
+	switch kind {
+	case hashPartial:
+		return e.PartialHash
+	case hashFull:
+		return e.FullHash
+	default:
+		h := e.Perceptual[algo]
+		if own, _, hasVariants := strings.Cut(h, ","); !transforms {
+			h = own
+		} else if !hasVariants {
+			h = "" // cached without the variants; hash again
+		}
+		return h
+	}
+}
+
+// put records f's hash of the given kind, if it has one.
+func (c *HashCache) put(f models.FileInfo, kind hashKind) {
+	if c == nil {
+		return
+	}
+	h := hashOf(f, kind)
+	if h == "" {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	now := time.Now().Unix()
+	e, ok := c.lookup(f, now)
+	if !ok {
+		e = cacheEntry{Size: f.Size, Modified: f.ModifiedNano, Inode: f.Inode, Seen: now}
+	}
+	switch kind {
+	case hashPartial:
+		e.PartialHash = h
+	case hashFull:
+		e.FullHash = h
+	case hashPerceptual:
+		ph, ok := parsePerceptualHash(h)
+		if !ok {
+			return
+		}
+		if e.Perceptual == nil {
+			e.Perceptual = make(map[models.HashAlgorithm]string)
+		}
+		e.Perceptual[ph.algo] = h
+	}
+	c.entries[f.Path] = e
+	c.dirty = true
+}
+
+// hashOf returns f's hash of the given kind.
+func hashOf(f models.FileInfo, kind hashKind) string {
+	switch kind {
+	case hashPartial:
+		return f.PartialHash
+	case hashFull:
+		return f.FullHash
+	default:
+		return f.PerceptualHash
+	}
+}
+
+// setHash sets f's hash of the given kind.
+func setHash(f *models.FileInfo, kind hashKind, h string) {
+	switch kind {
+	case hashPartial:
+		f.PartialHash = h
+	case hashFull:
+		f.FullHash = h
+	default:
+		f.PerceptualHash = h
+	}
+}
+
+// Save writes the cache to disk if it changed, evicting entries that have not
+// been seen for cacheMaxAge. The file is replaced atomically.
+func (c *HashCache) Save() error {
+	if c == nil {
+		return nil
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	cutoff := time.Now().Add(-cacheMaxAge).Unix()
+	for p, e := range c.entries {
+		if e.Seen < cutoff {
+			delete(c.entries, p)
+			c.dirty = true
+		}
+	}
+	if !c.dirty {
+		return nil
+	}
+
+	if err := os.MkdirAll(filepath.Dir(c.path), 0o755); err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(c.path), ".hashes-*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if err := gob.NewEncoder(tmp).Encode(cacheFile{Version: cacheVersion, Entries: c.entries}); err != nil {
+		tmp.Close()
+		return fmt.Errorf("encode hash cache: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp.Name(), c.path); err != nil {
+		return err
+	}
+
+	c.dirty = false
+	return nil
+}
+
+// Clear drops all entries and removes the cache file.
+func (c *HashCache) Clear() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.entries = make(map[string]cacheEntry)
+	c.dirty = false
+	if err := os.Remove(c.path); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	return nil
+}
+
+// Stats reports the number of cached paths and the size of the cache file.
+func (c *HashCache) Stats() models.CacheStats {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	stats := models.CacheStats{Path: c.path, Entries: len(c.entries)}
+	if info, err := os.Stat(c.path); err == nil {
+		stats.SizeBytes = info.Size()
+	}
+	return stats
+}
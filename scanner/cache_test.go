@@ -0,0 +1,31 @@
+package scanner
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"folder-cleaner-go/models"
+)
+
+func TestHashCacheRewriteWithinSecond(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "hashes.gob")
+	mtime := time.Date(2024, 5, 6, 7, 8, 9, 100, time.UTC)
+	f := models.FileInfo{Path: "/data/a", Size: 10, Modified: mtime.Unix(), ModifiedNano: mtime.UnixNano(), Inode: 7, FullHash: "old"}
+
+	c := OpenHashCache(path)
+	c.put(f, hashFull)
+	if err := c.Save(); err != nil {
+		t.Fatal(err)
+	}
+
+	c = OpenHashCache(path)
+	if got := c.get(f, hashFull, "", false); got != "old" {
+		t.Fatalf("unchanged file hash = %q, want %q", got, "old")
+	}
+	// Rewritten in the same second at the same size
+	f.ModifiedNano += int64(time.Millisecond)
+	if got := c.get(f, hashFull, "", false); got != "" {
+		t.Errorf("rewritten file hash = %q, want none", got)
+	}
+}
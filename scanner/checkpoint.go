@@ -0,0 +1,384 @@
+package scanner
+
+import (
+	"encoding/gob"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"sync"
+	"time"
+
+	"folder-cleaner-go/models"
+
+	"github.com/zeebo/blake3"
+)
+
+const (
+	// checkpointVersion changes whenever the checkpoint format does.
+	checkpointVersion = 2
+	// checkpointInterval is how often a running scan saves its hashes.
+	checkpointInterval = 30 * time.Second
+
+	checkpointWalkFile     = "walk.gob"
+	checkpointProgressFile = "progress.gob"
+)
+
+// A checkpoint is kept in two files, each a gob header followed by its data,
+// so the headers can be read without decoding everything:
+//
+//   - walk.gob holds the result of the walk, written once per scan.
+//   - progress.gob holds the hashes computed since, rewritten periodically.
+//
+// Both headers carry the scan's Started time, so a progress file is never
+// applied to the walk of another scan.
+type checkpointHeader struct {
+	Version      int
+	Key          string // checkpointKey of the scan's settings
+	Started      int64  // Unix nanoseconds at which the scan's walk finished
+	FilesScanned int
+}
+
+type checkpointProgress struct {
+	Header      checkpointHeader
+	Saved       int64    // Unix time of the save
+	Stages      []string // stages that had finished, in order
+	FilesHashed int
+}
+
+// checkpointWalk is the data of walk.gob: what the hashing stages start from.
+type checkpointWalk struct {
+	Groups  [][]models.FileInfo
+	Images  []models.FileInfo
+	Links   []models.FileInfo    // hard links collapsed out of Groups and Images
+	Skipped []models.SkippedFile // left out by the walk
+}
+
+// fileHashes are the hashes computed for one path.
+type fileHashes struct {
+	Partial, Full, Perceptual string
+}
+
+// checkpoint saves a scan's progress to a directory, so that a scan cut short
+// by a crash, shutdown or cancellation can be resumed by a new scan with the
+// same settings. Saving is best effort: a checkpoint that cannot be written
+// only means the scan cannot be resumed. A nil *checkpoint saves nothing.
+type checkpoint struct {
+	dir string
+	key string
+
+	mu      sync.Mutex
+	header  checkpointHeader
+	stages  []string
+	hashes  map[string]fileHashes
+	dirty   bool
+	written bool // the walk has been saved, so progress may be
+}
+
+func newCheckpoint(dir string, settings models.ScanSettings) *checkpoint {
+	if dir == "" {
+		return nil
+	}
+	return &checkpoint{dir: dir, key: checkpointKey(settings), hashes: make(map[string]fileHashes)}
+}
+
+// DefaultCheckpointDir returns where scans save their checkpoints.
+func DefaultCheckpointDir() (string, error) {
+	cacheDir, err := os.UserCacheDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(cacheDir, "ShadowWipe", "checkpoint"), nil
+}
+
+// checkpointKey identifies the settings that decide which files a scan walks
+// and how it hashes them. Settings that only affect grouping or speed, such
+// as the similarity threshold or I/O limits, may differ between a scan and
+// the one resuming it.
+func checkpointKey(s models.ScanSettings) string {
+	algo := s.HashAlgorithm
+	if algo == "" {
+		algo = models.HashPerception
+	}
+	data, _ := json.Marshal(struct {
+		Paths, ReferencePaths, ExcludedDirs []string
+		MinSize                             int64
+		SkipHidden, Similar, Transforms     bool
+		Algorithm                           models.HashAlgorithm
+	}{
+		s.Paths, s.ReferencePaths, s.ExcludedDirs,
+		s.MinFileSizeBytes(),
+		s.SkipHidden, s.SimilarityThreshold > 0, s.MatchTransforms,
+		algo,
+	})
+	sum := blake3.Sum256(data)
+	return hex.EncodeToString(sum[:16])
+}
+
+// FindCheckpoint describes the checkpoint in dir if it was left by an
+// unfinished scan with the same settings, or returns nil.
+func FindCheckpoint(dir string, settings models.ScanSettings) *models.ScanCheckpoint {
+	c := newCheckpoint(dir, settings)
+	if c == nil {
+		return nil
+	}
+	walk, ok := c.readHeader(checkpointWalkFile, &checkpointHeader{})
+	if !ok {
+		return nil
+	}
+
+	info := &models.ScanCheckpoint{FilesScanned: walk.FilesScanned}
+	var p checkpointProgress
+	if _, ok := c.readHeader(checkpointProgressFile, &p); ok && p.Header.Started == walk.Started {
+		info.FilesHashed = p.FilesHashed
+		info.Saved = p.Saved
+	} else {
+		p = checkpointProgress{}
+	}
+
+	// The hashing stages run concurrently, so the one a scan had reached is
+	// the earliest that had not finished, not the last that did
+	stages := hashStages(settings.SimilarityThreshold > 0)
+	info.Stage = stages[len(stages)-1]
+	for _, name := range stages {
+		if !slices.Contains(p.Stages, name) {
+			info.Stage = name
+			break
+		}
+	}
+	return info
+}
+
+// readHeader decodes the header of the named file into h, a *checkpointHeader
+// or *checkpointProgress, and reports whether it belongs to c's settings.
+func (c *checkpoint) readHeader(name string, h any) (checkpointHeader, bool) {
+	f, err := os.Open(filepath.Join(c.dir, name))
+	if err != nil {
+		return checkpointHeader{}, false
+	}
+	defer f.Close()
+	if err := gob.NewDecoder(f).Decode(h); err != nil {
+		return checkpointHeader{}, false
+	}
+
+	var hdr checkpointHeader
+	switch v := h.(type) {
+	case *checkpointHeader:
+		hdr = *v
+	case *checkpointProgress:
+		hdr = v.Header
+	}
+	return hdr, hdr.Version == checkpointVersion && hdr.Key == c.key
+}
+
+// load reads the saved walk and whatever hashes were saved after it, and
+// carries on recording into that checkpoint.
+func (c *checkpoint) load() (checkpointWalk, checkpointHeader, bool) {
+	if c == nil {
+		return checkpointWalk{}, checkpointHeader{}, false
+	}
+
+	var walk checkpointWalk
+	var hdr checkpointHeader
+	if err := readGob(filepath.Join(c.dir, checkpointWalkFile), &hdr, &walk); err != nil ||
+		hdr.Version != checkpointVersion || hdr.Key != c.key {
+		return checkpointWalk{}, checkpointHeader{}, false
+	}
+
+	var p checkpointProgress
+	hashes := make(map[string]fileHashes)
+	if err := readGob(filepath.Join(c.dir, checkpointProgressFile), &p, &hashes); err != nil ||
+		p.Header != hdr {
+		p, hashes = checkpointProgress{}, make(map[string]fileHashes)
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.header, c.stages, c.hashes, c.written = hdr, p.Stages, hashes, true
+	return walk, hdr, true
+}
+
+// hashesFor returns the saved hashes for path.
+func (c *checkpoint) hashesFor(path string) fileHashes {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.hashes[path]
+}
+
+// saveWalk starts a new checkpoint from the result of a walk, replacing any
+// earlier one.
+func (c *checkpoint) saveWalk(filesScanned int, w walkResult) error {
+	if c == nil {
+		return nil
+	}
+
+	c.mu.Lock()
+	c.header = checkpointHeader{
+		Version:      checkpointVersion,
+		Key:          c.key,
+		Started:      time.Now().UnixNano(),
+		FilesScanned: filesScanned,
+	}
+	c.stages, c.hashes, c.dirty, c.written = nil, make(map[string]fileHashes), true, false
+	hdr := c.header
+	c.mu.Unlock()
+
+	// Drop the old progress first, so it is never paired with the new walk
+	_ = os.Remove(filepath.Join(c.dir, checkpointProgressFile))
+
+	data := checkpointWalk{Groups: w.groups, Images: w.images, Skipped: w.skipped}
+	for _, paths := range w.links {
+		data.Links = append(data.Links, paths...)
+	}
+	if err := writeGob(filepath.Join(c.dir, checkpointWalkFile), hdr, data); err != nil {
+		return err
+	}
+
+	c.mu.Lock()
+	c.written = true
+	c.mu.Unlock()
+	return c.save()
+}
+
+// record notes a hash computed for path.
+func (c *checkpoint) record(path string, kind hashKind, h string) {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	fh := c.hashes[path]
+	switch kind {
+	case hashPartial:
+		fh.Partial = h
+	case hashFull:
+		fh.Full = h
+	default:
+		fh.Perceptual = h
+	}
+	c.hashes[path] = fh
+	c.dirty = true
+}
+
+// finishStage notes that a stage has hashed everything it was given.
+func (c *checkpoint) finishStage(name string) {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.stages = append(c.stages, name)
+	c.dirty = true
+}
+
+// save writes the recorded hashes if they changed since the last save.
+func (c *checkpoint) save() error {
+	if c == nil {
+		return nil
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if !c.dirty || !c.written {
+		return nil
+	}
+
+	p := checkpointProgress{
+		Header:      c.header,
+		Saved:       time.Now().Unix(),
+		Stages:      c.stages,
+		FilesHashed: len(c.hashes),
+	}
+	if err := writeGob(filepath.Join(c.dir, checkpointProgressFile), p, c.hashes); err != nil {
+		return err
+	}
+	c.dirty = false
+	return nil
+}
+
+// saveEvery saves the checkpoint every interval until the returned function
+// is called, which stops it and saves once more.
+func (c *checkpoint) saveEvery(interval time.Duration) (stop func()) {
+	if c == nil {
+		return func() {}
+	}
+	done := make(chan struct{})
+	stopped := make(chan struct{})
+	go func() {
+		defer close(stopped)
+		t := time.NewTicker(interval)
+		defer t.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case <-t.C:
+				_ = c.save()
+			}
+		}
+	}()
+	return func() {
+		close(done)
+		<-stopped
+		_ = c.save()
+	}
+}
+
+// remove deletes the checkpoint once its scan has completed.
+func (c *checkpoint) remove() {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.written = false
+	for _, name := range []string{checkpointProgressFile, checkpointWalkFile} {
+		_ = os.Remove(filepath.Join(c.dir, name))
+	}
+}
+
+// writeGob atomically replaces path with the gob encoding of values.
+func writeGob(path string, values ...any) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".checkpoint-*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	enc := gob.NewEncoder(tmp)
+	for _, v := range values {
+		if err := enc.Encode(v); err != nil {
+			tmp.Close()
+			return err
+		}
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+// readGob decodes consecutive gob values from path into values.
+func readGob(path string, values ...any) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	dec := gob.NewDecoder(f)
+	for _, v := range values {
+		if err := dec.Decode(v); err != nil {
+			if err == io.EOF {
+				return io.ErrUnexpectedEOF
+			}
+			return err
+		}
+	}
+	return nil
+}
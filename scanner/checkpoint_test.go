@@ -0,0 +1,285 @@
+package scanner
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"folder-cleaner-go/models"
+)
+
+func checkpointSettings() models.ScanSettings {
+	return models.ScanSettings{
+		Paths:               []string{"/photos"},
+		MinFileSize:         1,
+		MinFileSizeUnit:     "KB",
+		SimilarityThreshold: 10,
+	}
+}
+
+func testWalk() walkResult {
+	a := models.FileInfo{Path: "/photos/a.jpg", Size: 2048}
+	b := models.FileInfo{Path: "/photos/b.jpg", Size: 2048}
+	return walkResult{
+		groups: [][]models.FileInfo{{a, b}},
+		images: []models.FileInfo{a, b},
+		links: map[inodeKey][]models.FileInfo{
+			{dev: 1, ino: 2}: {{Path: "/photos/a-link.jpg", Size: 2048}},
+		},
+		skipped: []models.SkippedFile{{Path: "/photos/locked", Reason: models.SkipPermission, Stage: "walking"}},
+	}
+}
+
+func TestCheckpointRoundTrip(t *testing.T) {
+	dir := t.TempDir()
+	settings := checkpointSettings()
+
+	c := newCheckpoint(dir, settings)
+	if err := c.saveWalk(3, testWalk()); err != nil {
+		t.Fatal(err)
+	}
+	c.record("/photos/a.jpg", hashPartial, "p1")
+	c.record("/photos/a.jpg", hashFull, "f1")
+	c.record("/photos/b.jpg", hashPerceptual, "perception:00ff")
+	c.finishStage("partial-hashing")
+	if err := c.save(); err != nil {
+		t.Fatal(err)
+	}
+
+	r := newCheckpoint(dir, settings)
+	walk, hdr, ok := r.load()
+	if !ok {
+		t.Fatal("load found no checkpoint")
+	}
+	if hdr != c.header {
+		t.Errorf("header = %+v, want %+v", hdr, c.header)
+	}
+	w := testWalk()
+	want := checkpointWalk{Groups: w.groups, Images: w.images, Links: w.links[inodeKey{1, 2}], Skipped: w.skipped}
+	if !reflect.DeepEqual(walk, want) {
+		t.Errorf("walk = %+v, want %+v", walk, want)
+	}
+	if got := r.hashesFor("/photos/a.jpg"); got != (fileHashes{Partial: "p1", Full: "f1"}) {
+		t.Errorf("hashes for a = %+v", got)
+	}
+	if got := r.hashesFor("/photos/b.jpg"); got != (fileHashes{Perceptual: "perception:00ff"}) {
+		t.Errorf("hashes for b = %+v", got)
+	}
+	if !reflect.DeepEqual(r.stages, []string{"partial-hashing"}) {
+		t.Errorf("stages = %v", r.stages)
+	}
+
+	// The resumed checkpoint carries on recording into the same files
+	r.record("/photos/b.jpg", hashPartial, "p2")
+	if err := r.save(); err != nil {
+		t.Fatal(err)
+	}
+	if info := FindCheckpoint(dir, settings); info == nil || info.FilesHashed != 2 {
+		t.Errorf("after resuming: %+v, want 2 files hashed", info)
+	}
+
+	r.remove()
+	if info := FindCheckpoint(dir, settings); info != nil {
+		t.Errorf("found %+v after remove", info)
+	}
+}
+
+func TestFindCheckpoint(t *testing.T) {
+	dir := t.TempDir()
+	settings := checkpointSettings()
+	if info := FindCheckpoint(dir, settings); info != nil {
+		t.Fatalf("found %+v in an empty directory", info)
+	}
+
+	c := newCheckpoint(dir, settings)
+	if err := c.saveWalk(7, testWalk()); err != nil {
+		t.Fatal(err)
+	}
+	info := FindCheckpoint(dir, settings)
+	if info == nil {
+		t.Fatal("no checkpoint after the walk was saved")
+	}
+	if info.FilesScanned != 7 || info.FilesHashed != 0 || info.Stage != "partial-hashing" {
+		t.Errorf("after the walk: %+v", info)
+	}
+
+	c.record("/photos/a.jpg", hashPartial, "p1")
+	c.finishStage("partial-hashing")
+	if err := c.save(); err != nil {
+		t.Fatal(err)
+	}
+	info = FindCheckpoint(dir, settings)
+	if info == nil || info.FilesHashed != 1 || info.Stage != "full-hashing" || info.Saved == 0 {
+		t.Errorf("after partial hashing: %+v", info)
+	}
+}
+
+func TestFindCheckpointStage(t *testing.T) {
+	tests := []struct {
+		name     string
+		similar  bool
+		finished []string
+		want     string
+	}{
+		{"nothing finished", false, nil, "partial-hashing"},
+		{"partial finished", false, []string{"partial-hashing"}, "full-hashing"},
+		{"all finished", false, []string{"partial-hashing", "full-hashing"}, "full-hashing"},
+		{"perceptual finished first", true, []string{"perceptual-hashing"}, "partial-hashing"},
+		{"perceptual still running", true, []string{"partial-hashing", "full-hashing"}, "perceptual-hashing"},
+		{"similar off with partial unfinished", false, []string{"full-hashing"}, "partial-hashing"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			settings := checkpointSettings()
+			if !tt.similar {
+				settings.SimilarityThreshold = 0
+			}
+			c := newCheckpoint(dir, settings)
+			if err := c.saveWalk(1, testWalk()); err != nil {
+				t.Fatal(err)
+			}
+			for _, s := range tt.finished {
+				c.finishStage(s)
+			}
+			if err := c.save(); err != nil {
+				t.Fatal(err)
+			}
+			info := FindCheckpoint(dir, settings)
+			if info == nil {
+				t.Fatal("no checkpoint")
+			}
+			if info.Stage != tt.want {
+				t.Errorf("stage = %q, want %q", info.Stage, tt.want)
+			}
+		})
+	}
+}
+
+func TestCheckpointRejectsOtherSettings(t *testing.T) {
+	base := checkpointSettings()
+	tests := []struct {
+		name   string
+		change func(*models.ScanSettings)
+		same   bool
+	}{
+		{"paths", func(s *models.ScanSettings) { s.Paths = []string{"/music"} }, false},
+		{"reference paths", func(s *models.ScanSettings) { s.ReferencePaths = []string{"/backup"} }, false},
+		{"excluded dirs", func(s *models.ScanSettings) { s.ExcludedDirs = []string{"/photos/tmp"} }, false},
+		{"min size", func(s *models.ScanSettings) { s.MinFileSizeUnit = "MB" }, false},
+		{"skip hidden", func(s *models.ScanSettings) { s.SkipHidden = true }, false},
+		{"similarity off", func(s *models.ScanSettings) { s.SimilarityThreshold = 0 }, false},
+		{"transforms", func(s *models.ScanSettings) { s.MatchTransforms = true }, false},
+		{"algorithm", func(s *models.ScanSettings) { s.HashAlgorithm = models.HashDifference }, false},
+		{"default algorithm spelled out", func(s *models.ScanSettings) { s.HashAlgorithm = models.HashPerception }, true},
+		{"threshold", func(s *models.ScanSettings) { s.SimilarityThreshold = 4 }, true},
+		{"cluster mode", func(s *models.ScanSettings) { s.ClusterMode = models.ClusterComplete }, true},
+		{"read rate", func(s *models.ScanSettings) { s.MaxReadRate = 1 << 20 }, true},
+		{"io limits", func(s *models.ScanSettings) { s.IOLimits.Rotational = 4 }, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			if err := newCheckpoint(dir, base).saveWalk(1, testWalk()); err != nil {
+				t.Fatal(err)
+			}
+			other := base
+			tt.change(&other)
+
+			info := FindCheckpoint(dir, other)
+			_, _, loaded := newCheckpoint(dir, other).load()
+			if (info != nil) != tt.same || loaded != tt.same {
+				t.Errorf("found %v, loaded %v; want both %v", info != nil, loaded, tt.same)
+			}
+		})
+	}
+}
+
+func TestCheckpointIgnoresStaleProgress(t *testing.T) {
+	dir := t.TempDir()
+	settings := checkpointSettings()
+	c := newCheckpoint(dir, settings)
+	if err := c.saveWalk(1, testWalk()); err != nil {
+		t.Fatal(err)
+	}
+	c.record("/photos/a.jpg", hashPartial, "p1")
+	c.finishStage("partial-hashing")
+	if err := c.save(); err != nil {
+		t.Fatal(err)
+	}
+
+	// Progress written for another walk is not applied to this one
+	p := checkpointProgress{Header: c.header, Stages: []string{"partial-hashing"}, FilesHashed: 1}
+	p.Header.Started++
+	if err := writeGob(filepath.Join(dir, checkpointProgressFile), p, map[string]fileHashes{"/photos/a.jpg": {Partial: "p1"}}); err != nil {
+		t.Fatal(err)
+	}
+	info := FindCheckpoint(dir, settings)
+	if info == nil || info.FilesHashed != 0 || info.Saved != 0 || info.Stage != "partial-hashing" {
+		t.Errorf("with stale progress: %+v", info)
+	}
+	r := newCheckpoint(dir, settings)
+	if _, _, ok := r.load(); !ok {
+		t.Fatal("walk not loaded")
+	}
+	if got := r.hashesFor("/photos/a.jpg"); got != (fileHashes{}) {
+		t.Errorf("stale hashes loaded: %+v", got)
+	}
+
+	// A new walk drops the progress of the old one
+	c = newCheckpoint(dir, settings)
+	if err := c.saveWalk(1, testWalk()); err != nil {
+		t.Fatal(err)
+	}
+	if info := FindCheckpoint(dir, settings); info == nil || info.FilesHashed != 0 {
+		t.Errorf("after a new walk: %+v", info)
+	}
+}
+
+func TestCheckpointRejectsBadFiles(t *testing.T) {
+	settings := checkpointSettings()
+
+	dir := t.TempDir()
+	hdr := checkpointHeader{Version: checkpointVersion + 1, Key: checkpointKey(settings)}
+	if err := writeGob(filepath.Join(dir, checkpointWalkFile), hdr, checkpointWalk{}); err != nil {
+		t.Fatal(err)
+	}
+	if info := FindCheckpoint(dir, settings); info != nil {
+		t.Errorf("found %+v with another version", info)
+	}
+
+	dir = t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, checkpointWalkFile), []byte("not a gob"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if info := FindCheckpoint(dir, settings); info != nil {
+		t.Errorf("found %+v in a corrupt file", info)
+	}
+	if _, _, ok := newCheckpoint(dir, settings).load(); ok {
+		t.Error("loaded a corrupt file")
+	}
+}
+
+func TestNilCheckpoint(t *testing.T) {
+	c := newCheckpoint("", checkpointSettings())
+	if c != nil {
+		t.Fatal("a checkpoint without a directory is not nil")
+	}
+	if err := c.saveWalk(1, testWalk()); err != nil {
+		t.Error(err)
+	}
+	c.record("/photos/a.jpg", hashPartial, "p1")
+	c.finishStage("partial-hashing")
+	if err := c.save(); err != nil {
+		t.Error(err)
+	}
+	if _, _, ok := c.load(); ok {
+		t.Error("nil checkpoint loaded")
+	}
+	c.saveEvery(checkpointInterval)()
+	c.remove()
+	if FindCheckpoint("", checkpointSettings()) != nil {
+		t.Error("found a checkpoint without a directory")
+	}
+}
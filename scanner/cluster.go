@@ -0,0 +1,430 @@
+package scanner
+
+import (
+	"container/heap"
+	"context"
+	"math"
+	"sort"
+
+	"folder-cleaner-go/models"
+)
+
+// hashedImage is an image with its parsed perceptual hash and, when matching
+// transforms, the hashes of its variants in imageTransforms order.
+type hashedImage struct {
+	file     models.FileInfo
+	hash     []uint64
+	variants [][]uint64
+}
+
+// similarCluster is a group of similar images, representative first, and the
+// largest Hamming distance between any two of them. For clusters above
+// exactClusterSize with pairs beyond the search distance, maxDistance is
+// the largest distance known, and at least one more than the search distance.
+type similarCluster struct {
+	files       []models.FileInfo
+	maxDistance int
+}
+
+// similarityPercent converts a Hamming distance between hashes of the given
+// length into the share of matching bits, rounded to one decimal place.
+func similarityPercent(distance, hashBits int) float64 {
+	return math.Round(1000*float64(hashBits-distance)/float64(hashBits)) / 10
+}
+
+// groupBySimilarHash groups images with similar perceptual hashes using the
+// given clustering mode. distance is the maximum Hamming distance to consider
+// as similar. Only hashes from the same algorithm are compared. With
+// transforms, rotated and mirrored variants are compared as well. Images are
+// ordered by path first, so results do not depend on the order in which
+// files were walked.
+func groupBySimilarHash(ctx context.Context, files []models.FileInfo, distance int, mode models.ClusterMode, transforms bool) ([]similarCluster, error) {
+	byAlgo := make(map[models.HashAlgorithm][]hashedImage)
+	var algos []models.HashAlgorithm
+	for _, f := range files {
+		if f.PerceptualHash == "" {
+			continue
+		}
+		ph, ok := parsePerceptualHash(f.PerceptualHash)
+		if !ok {
+			continue
+		}
+		img := hashedImage{file: f, hash: ph.words}
+		if transforms {
+			img.variants = ph.variants
+		}
+		if _, seen := byAlgo[ph.algo]; !seen {
+			algos = append(algos, ph.algo)
+		}
+		byAlgo[ph.algo] = append(byAlgo[ph.algo], img)
+	}
+	sort.Slice(algos, func(i, j int) bool { return algos[i] < algos[j] })
+
+	var result []similarCluster
+	for _, algo := range algos {
+		clusters, err := clusterImages(ctx, byAlgo[algo], distance, mode, algo.HashBits())
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, clusters...)
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].files[0].Path < result[j].files[0].Path })
+	return result, nil
+}
+
+// clusterImages clusters images whose hashes are all hashBits long. Pairs are
+// found through a multi-index hash table rather than by comparing every
+// image with every other.
+func clusterImages(ctx context.Context, images []hashedImage, distance int, mode models.ClusterMode, hashBits int) ([]similarCluster, error) {
+	if len(images) < 2 {
+		return nil, nil
+	}
+	sort.Slice(images, func(i, j int) bool { return images[i].file.Path < images[j].file.Path })
+
+	words := hashBits / 64
+	hashes := make([]uint64, 0, len(images)*words)
+	for _, img := range images {
+		hashes = append(hashes, img.hash...)
+	}
+	neighbours, err := findNeighbours(ctx, hashes, variantHashes(images, words), words, distance)
+	if err != nil {
+		return nil, err
+	}
+
+	var clusters [][]int
+	switch mode {
+	case models.ClusterConnected:
+		clusters = connectedComponents(neighbours)
+	case models.ClusterComplete:
+		for _, c := range connectedComponents(neighbours) {
+			split, err := completeLinkage(ctx, neighbours, c)
+			if err != nil {
+				return nil, err
+			}
+			clusters = append(clusters, split...)
+		}
+	default:
+		clusters = greedyClusters(neighbours)
+	}
+
+	var result []similarCluster
+	for _, c := range clusters {
+		if len(c) < 2 {
+			continue
+		}
+		sort.Ints(c)
+		sc, err := newSimilarCluster(ctx, images, neighbours, c, distance, hashBits)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, sc)
+	}
+	return result, nil
+}
+
+// variantHashes lays out the images' variant hashes for findNeighbours, one
+// slice per transform. Images hashed without variants stand in for their own.
+// It returns nil if no image has variants.
+func variantHashes(images []hashedImage, words int) [][]uint64 {
+	hasVariants := false
+	for _, img := range images {
+		if img.variants != nil {
+			hasVariants = true
+			break
+		}
+	}
+	if !hasVariants {
+		return nil
+	}
+
+	variants := make([][]uint64, len(imageTransforms))
+	for t := range variants {
+		variants[t] = make([]uint64, 0, len(images)*words)
+		for _, img := range images {
+			if img.variants != nil {
+				variants[t] = append(variants[t], img.variants[t]...)
+			} else {
+				variants[t] = append(variants[t], img.hash...)
+			}
+		}
+	}
+	return variants
+}
+
+// pairDistance returns the smallest distance between a and b, trying each
+// variant of either, and the transform that turns a into b at that distance.
+// Ties go to no transform, then to imageTransforms order.
+func pairDistance(a, b hashedImage) (int, models.ImageTransform) {
+	d, tr := hashDistance(a.hash, b.hash), models.TransformNone
+	for k, t := range imageTransforms {
+		if a.variants != nil {
+			if v := hashDistance(a.variants[k], b.hash); v < d {
+				d, tr = v, t.transform
+			}
+		}
+		if b.variants != nil {
+			if v := hashDistance(a.hash, b.variants[k]); v < d {
+				d, tr = v, t.transform.Inverse()
+			}
+		}
+	}
+	return d, tr
+}
+
+// exactClusterSize is the largest cluster whose pairwise distances are all
+// computed. Larger ones, such as long chains of burst photos joined in
+// connected mode, take them from the neighbour lists.
+const exactClusterSize = 64
+
+// newSimilarCluster builds a cluster from image indices in path order. The
+// representative is the image with the smallest worst-case distance to the
+// others (ties go to the smaller total distance, then to path order); every
+// file records its distance and similarity to it, and how it is rotated or
+// mirrored relative to it. Above exactClusterSize, a pair missing from the
+// neighbour lists counts as one more than the search distance, the least it
+// can be, so the cost stays with the neighbour pairs.
+func newSimilarCluster(ctx context.Context, images []hashedImage, neighbours [][]neighbour, members []int, distance, hashBits int) (similarCluster, error) {
+	n := len(members)
+	worst := make([]int, n)
+	total := make([]int, n)
+	var sc similarCluster
+	pair := func(a, b, d int) {
+		worst[a], worst[b] = max(worst[a], d), max(worst[b], d)
+		total[a] += d
+		total[b] += d
+		sc.maxDistance = max(sc.maxDistance, d)
+	}
+
+	if n <= exactClusterSize {
+		for a := 0; a < n; a++ {
+			if err := ctx.Err(); err != nil {
+				return similarCluster{}, err
+			}
+			for b := a + 1; b < n; b++ {
+				d, _ := pairDistance(images[members[a]], images[members[b]])
+				pair(a, b, d)
+			}
+		}
+	} else {
+		local := make(map[int]int, n)
+		for a, m := range members {
+			local[m] = a
+		}
+		known := make([]int, n)
+		for a, m := range members {
+			if err := ctx.Err(); err != nil {
+				return similarCluster{}, err
+			}
+			for _, nb := range neighbours[m] {
+				if b, ok := local[int(nb.index)]; ok {
+					pair(a, b, int(nb.distance))
+					known[a]++
+					known[b]++
+				}
+			}
+		}
+		for a := range members {
+			if missing := n - 1 - known[a]; missing > 0 {
+				worst[a] = max(worst[a], distance+1)
+				total[a] += missing * (distance + 1)
+				sc.maxDistance = max(sc.maxDistance, distance+1)
+			}
+		}
+	}
+
+	rep := 0
+	for a := 1; a < n; a++ {
+		if worst[a] < worst[rep] || (worst[a] == worst[rep] && total[a] < total[rep]) {
+			rep = a
+		}
+	}
+
+	order := append([]int{members[rep]}, members[:rep]...)
+	order = append(order, members[rep+1:]...)
+	sc.files = make([]models.FileInfo, n)
+	for i, idx := range order {
+		f := images[idx].file
+		f.Distance, f.Transform = pairDistance(images[members[rep]], images[idx])
+		f.Similarity = similarityPercent(f.Distance, hashBits)
+		sc.maxDistance = max(sc.maxDistance, f.Distance)
+		sc.files[i] = f
+	}
+	return sc, nil
+}
+
+// greedyClusters anchors each group on the first unused image and adds every
+// later unused image within distance of that anchor.
+func greedyClusters(neighbours [][]neighbour) [][]int {
+	used := make([]bool, len(neighbours))
+	var clusters [][]int
+	for i, ns := range neighbours {
+		if used[i] {
+			continue
+		}
+		group := []int{i}
+		for _, n := range ns {
+			if !used[n.index] {
+				group = append(group, int(n.index))
+				used[n.index] = true
+			}
+		}
+		if len(group) >= 2 {
+			used[i] = true
+			clusters = append(clusters, group)
+		}
+	}
+	return clusters
+}
+
+// connectedComponents groups images linked by any chain of neighbours, using
+// union-find.
+func connectedComponents(neighbours [][]neighbour) [][]int {
+	parent := make([]int, len(neighbours))
+	for i := range parent {
+		parent[i] = i
+	}
+	var find func(int) int
+	find = func(i int) int {
+		for parent[i] != i {
+			parent[i] = parent[parent[i]]
+			i = parent[i]
+		}
+		return i
+	}
+
+	for i, ns := range neighbours {
+		for _, n := range ns {
+			// Root at the lower index so components are labelled stably
+			a, b := find(i), find(int(n.index))
+			if a > b {
+				a, b = b, a
+			}
+			if a != b {
+				parent[b] = a
+			}
+		}
+	}
+
+	byRoot := make(map[int][]int)
+	var roots []int
+	for i := range neighbours {
+		r := find(i)
+		if _, ok := byRoot[r]; !ok {
+			roots = append(roots, r)
+		}
+		byRoot[r] = append(byRoot[r], i)
+	}
+	clusters := make([][]int, 0, len(roots))
+	for _, r := range roots {
+		if len(byRoot[r]) >= 2 {
+			clusters = append(clusters, byRoot[r])
+		}
+	}
+	return clusters
+}
+
+// linkStat summarises the neighbour pairs between two clusters.
+type linkStat struct {
+	pairs, maxDistance int
+}
+
+// merge is a candidate merge of clusters a < b at the given distance.
+type merge struct {
+	distance, a, b int
+}
+
+// mergeHeap is a min-heap of merges by distance, then by a and b.
+type mergeHeap []merge
+
+func (h mergeHeap) Len() int { return len(h) }
+func (h mergeHeap) Less(i, j int) bool {
+	if h[i].distance != h[j].distance {
+		return h[i].distance < h[j].distance
+	}
+	if h[i].a != h[j].a {
+		return h[i].a < h[j].a
+	}
+	return h[i].b < h[j].b
+}
+func (h mergeHeap) Swap(i, j int) { h[i], h[j] = h[j], h[i] }
+func (h *mergeHeap) Push(x any)   { *h = append(*h, x.(merge)) }
+func (h *mergeHeap) Pop() any {
+	old := *h
+	m := old[len(old)-1]
+	*h = old[:len(old)-1]
+	return m
+}
+
+// completeLinkage splits one connected component into clusters whose
+// diameter is within the search distance. It repeatedly merges the two
+// clusters with the smallest maximum pairwise distance, breaking ties by
+// index. Two clusters may only merge when every cross pair is a neighbour,
+// so only the sparse neighbour pairs are ever stored. Candidate merges wait
+// in a heap and are checked against the current links when taken, so each
+// merge only costs the links of the cluster merged away.
+func completeLinkage(ctx context.Context, neighbours [][]neighbour, members []int) ([][]int, error) {
+	n := len(members)
+	local := make(map[int]int, n)
+	for a, m := range members {
+		local[m] = a
+	}
+
+	clusters := make([][]int, n)
+	links := make([]map[int]linkStat, n)
+	for a, m := range members {
+		clusters[a] = []int{m}
+		links[a] = make(map[int]linkStat)
+	}
+	var queue mergeHeap
+	for a, m := range members {
+		for _, nb := range neighbours[m] {
+			b := local[int(nb.index)]
+			st := linkStat{pairs: 1, maxDistance: int(nb.distance)}
+			links[a][b], links[b][a] = st, st
+			queue = append(queue, merge{distance: st.maxDistance, a: min(a, b), b: max(a, b)})
+		}
+	}
+	heap.Init(&queue)
+
+	for queue.Len() > 0 {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		m := heap.Pop(&queue).(merge)
+		a, b := m.a, m.b
+		if clusters[a] == nil || clusters[b] == nil {
+			continue
+		}
+		// Skip merges the links have changed since they were queued
+		st, ok := links[a][b]
+		if !ok || st.maxDistance != m.distance || st.pairs != len(clusters[a])*len(clusters[b]) {
+			continue
+		}
+
+		clusters[a] = append(clusters[a], clusters[b]...)
+		clusters[b] = nil
+		delete(links[a], b)
+		for c, st := range links[b] {
+			if c == a {
+				continue
+			}
+			merged := links[a][c]
+			merged.pairs += st.pairs
+			merged.maxDistance = max(merged.maxDistance, st.maxDistance)
+			links[a][c], links[c][a] = merged, merged
+			delete(links[c], b)
+			if merged.pairs == len(clusters[a])*len(clusters[c]) {
+				heap.Push(&queue, merge{distance: merged.maxDistance, a: min(a, c), b: max(a, c)})
+			}
+		}
+		links[b] = nil
+	}
+
+	var out [][]int
+	for _, c := range clusters {
+		if c != nil {
+			out = append(out, c)
+		}
+	}
+	return out, nil
+}
@@ -0,0 +1,218 @@
+package scanner
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"sort"
+	"strconv"
+	"testing"
+
+	"folder-cleaner-go/models"
+)
+
+// hashedFiles builds files named after their keys with 64-bit pHashes.
+func hashedFiles(hashes map[string]uint64) []models.FileInfo {
+	var files []models.FileInfo
+	for name, h := range hashes {
+		files = append(files, models.FileInfo{
+			Path:           name,
+			PerceptualHash: formatPerceptualHash(perceptualHash{algo: models.HashPerception, words: []uint64{h}}),
+		})
+	}
+	return files
+}
+
+// clusterPaths returns each cluster's paths sorted, with the clusters sorted
+// by their first path, so results compare independently of representatives.
+func clusterPaths(clusters []similarCluster) [][]string {
+	out := make([][]string, 0, len(clusters))
+	for _, c := range clusters {
+		var paths []string
+		for _, f := range c.files {
+			paths = append(paths, f.Path)
+		}
+		sort.Strings(paths)
+		out = append(out, paths)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i][0] < out[j][0] })
+	return out
+}
+
+func TestClusterModes(t *testing.T) {
+	// Each step of the chain is one bit, so a and d are three apart.
+	chain := map[string]uint64{"a": 0b000, "b": 0b001, "c": 0b011, "d": 0b111}
+	// a is two bits from every other image, but b and d are four apart.
+	star := map[string]uint64{"a": 0b0000, "b": 0b0011, "c": 0b0101, "d": 0b1100}
+	// Two unrelated pairs and an outlier.
+	separate := map[string]uint64{"a": 0, "b": 1, "c": ^uint64(0), "d": ^uint64(0) ^ 3, "e": 0xFFFF0000}
+
+	tests := []struct {
+		name     string
+		hashes   map[string]uint64
+		distance int
+		mode     models.ClusterMode
+		want     [][]string
+	}{
+		{"chain greedy", chain, 1, models.ClusterGreedy, [][]string{{"a", "b"}, {"c", "d"}}},
+		{"chain connected", chain, 1, models.ClusterConnected, [][]string{{"a", "b", "c", "d"}}},
+		{"chain complete", chain, 1, models.ClusterComplete, [][]string{{"a", "b"}, {"c", "d"}}},
+		{"star greedy", star, 2, models.ClusterGreedy, [][]string{{"a", "b", "c", "d"}}},
+		{"star connected", star, 2, models.ClusterConnected, [][]string{{"a", "b", "c", "d"}}},
+		{"star complete", star, 2, models.ClusterComplete, [][]string{{"a", "b", "c"}}},
+		{"separate greedy", separate, 2, models.ClusterGreedy, [][]string{{"a", "b"}, {"c", "d"}}},
+		{"separate connected", separate, 2, models.ClusterConnected, [][]string{{"a", "b"}, {"c", "d"}}},
+		{"separate complete", separate, 2, models.ClusterComplete, [][]string{{"a", "b"}, {"c", "d"}}},
+		{"nothing within distance", chain, 0, models.ClusterConnected, [][]string{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			clusters, err := groupBySimilarHash(context.Background(), hashedFiles(tt.hashes), tt.distance, tt.mode, false)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := clusterPaths(clusters); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("clusters = %v, want %v", got, tt.want)
+			}
+			if tt.mode == models.ClusterComplete {
+				for _, c := range clusters {
+					if c.maxDistance > tt.distance {
+						t.Errorf("cluster %v has diameter %d, beyond %d", clusterPaths([]similarCluster{c}), c.maxDistance, tt.distance)
+					}
+				}
+			}
+		})
+	}
+}
+
+func TestClusterRepresentative(t *testing.T) {
+	// b is one bit from both a and c, which are two apart.
+	files := hashedFiles(map[string]uint64{"a": 0b01, "b": 0b11, "c": 0b10})
+	clusters, err := groupBySimilarHash(context.Background(), files, 2, models.ClusterGreedy, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(clusters) != 1 {
+		t.Fatalf("got %d clusters, want 1", len(clusters))
+	}
+	c := clusters[0]
+	if c.files[0].Path != "b" || c.files[0].Distance != 0 || c.files[0].Similarity != 100 {
+		t.Errorf("representative = %+v, want b at distance 0", c.files[0])
+	}
+	for _, f := range c.files[1:] {
+		if f.Distance != 1 {
+			t.Errorf("%s distance = %d, want 1", f.Path, f.Distance)
+		}
+	}
+	if c.maxDistance != 2 {
+		t.Errorf("maxDistance = %d, want 2", c.maxDistance)
+	}
+}
+
+func TestClusterSeparatesAlgorithms(t *testing.T) {
+	files := []models.FileInfo{
+		{Path: "a", PerceptualHash: formatPerceptualHash(perceptualHash{algo: models.HashPerception, words: []uint64{42}})},
+		{Path: "b", PerceptualHash: formatPerceptualHash(perceptualHash{algo: models.HashAverage, words: []uint64{42}})},
+	}
+	clusters, err := groupBySimilarHash(context.Background(), files, 4, models.ClusterGreedy, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(clusters) != 0 {
+		t.Errorf("hashes from different algorithms were clustered: %v", clusterPaths(clusters))
+	}
+}
+
+func TestClusterTransforms(t *testing.T) {
+	const a, b = 0x0123456789ABCDEF, 0xFEDCBA9876543210
+	// b rotated 90 degrees hashes exactly like a; its other variants are far
+	// from everything.
+	variants := [][]uint64{{a}, {0x00FF00FF00FF00FF}, {0x0F0F0F0F0F0F0F0F}, {0x3333333333333333}}
+	files := []models.FileInfo{
+		{Path: "a", PerceptualHash: formatPerceptualHash(perceptualHash{algo: models.HashPerception, words: []uint64{a}})},
+		{Path: "b", PerceptualHash: formatPerceptualHash(perceptualHash{algo: models.HashPerception, words: []uint64{b}, variants: variants})},
+	}
+
+	clusters, err := groupBySimilarHash(context.Background(), files, 4, models.ClusterGreedy, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(clusters) != 0 {
+		t.Fatalf("clustered without transforms: %v", clusterPaths(clusters))
+	}
+
+	clusters, err = groupBySimilarHash(context.Background(), files, 4, models.ClusterGreedy, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(clusters) != 1 {
+		t.Fatalf("got %d clusters with transforms, want 1", len(clusters))
+	}
+	got := clusters[0].files
+	if got[0].Path != "a" || got[1].Path != "b" {
+		t.Fatalf("cluster = %v, want [a b]", clusterPaths(clusters))
+	}
+	// a turns into b by undoing b's 90 degree rotation
+	if got[1].Distance != 0 || got[1].Transform != models.TransformRotate270 {
+		t.Errorf("b distance %d transform %q, want 0 and %q", got[1].Distance, got[1].Transform, models.TransformRotate270)
+	}
+}
+
+// chainFiles builds n images whose hashes each differ from the next by one
+// bit, so at distance 1 they form a single connected chain.
+func chainFiles(n int) map[string]uint64 {
+	hashes := make(map[string]uint64, n)
+	for k := 0; k < n; k++ {
+		hashes[fmt.Sprintf("%03d", k)] = uint64(1)<<k - 1
+	}
+	return hashes
+}
+
+func TestClusterLargeChain(t *testing.T) {
+	const n = exactClusterSize + 1
+	clusters, err := groupBySimilarHash(context.Background(), hashedFiles(chainFiles(n)), 1, models.ClusterConnected, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(clusters) != 1 || len(clusters[0].files) != n {
+		t.Fatalf("clusters = %v, want one chain of %d", clusterPaths(clusters), n)
+	}
+	c := clusters[0]
+	// Far pairs are not computed, but the distances to the representative are
+	rep := c.files[0]
+	for _, f := range c.files {
+		if want := bitsApart(rep.Path, f.Path); f.Distance != want {
+			t.Errorf("%s distance = %d, want %d", f.Path, f.Distance, want)
+		}
+		if f.Distance > c.maxDistance {
+			t.Errorf("%s is %d from the representative, beyond maxDistance %d", f.Path, f.Distance, c.maxDistance)
+		}
+	}
+
+	clusters, err = groupBySimilarHash(context.Background(), hashedFiles(chainFiles(n)), 1, models.ClusterComplete, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, c := range clusters {
+		if len(c.files) != 2 || c.maxDistance != 1 {
+			t.Errorf("complete cluster %v has diameter %d, want a pair at 1", clusterPaths([]similarCluster{c}), c.maxDistance)
+		}
+	}
+}
+
+// bitsApart is the distance between two chainFiles images by name.
+func bitsApart(a, b string) int {
+	i, _ := strconv.Atoi(a)
+	j, _ := strconv.Atoi(b)
+	return max(i-j, j-i)
+}
+
+func TestClusterCancelled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	for _, mode := range []models.ClusterMode{models.ClusterGreedy, models.ClusterConnected, models.ClusterComplete} {
+		if _, err := groupBySimilarHash(ctx, hashedFiles(chainFiles(exactClusterSize+1)), 1, mode, false); err != context.Canceled {
+			t.Errorf("%s: err = %v, want %v", mode, err, context.Canceled)
+		}
+	}
+}
@@ -0,0 +1,41 @@
+//go:build linux
+
+package scanner
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"folder-cleaner-go/models"
+
+	"golang.org/x/sys/unix"
+)
+
+// deviceType classifies the block device with the given st_dev from sysfs.
+// Devices without a block device entry (network and virtual filesystems,
+// btrfs subvolumes) are unknown.
+func deviceType(dev uint64) models.DeviceType {
+	dir, err := filepath.EvalSymlinks(fmt.Sprintf("/sys/dev/block/%d:%d", unix.Major(dev), unix.Minor(dev)))
+	if err != nil {
+		return models.DeviceUnknown
+	}
+	// Partitions keep their queue settings on the parent disk
+	if _, err := os.Stat(filepath.Join(dir, "partition")); err == nil {
+		dir = filepath.Dir(dir)
+	}
+
+	rotational, err := os.ReadFile(filepath.Join(dir, "queue", "rotational"))
+	if err != nil {
+		return models.DeviceUnknown
+	}
+	switch {
+	case strings.TrimSpace(string(rotational)) == "1":
+		return models.DeviceRotational
+	case strings.HasPrefix(filepath.Base(dir), "nvme"):
+		return models.DeviceNVMe
+	default:
+		return models.DeviceSSD
+	}
+}
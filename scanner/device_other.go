@@ -0,0 +1,10 @@
+//go:build !linux
+
+package scanner
+
+import "folder-cleaner-go/models"
+
+// deviceType cannot tell storage types apart on this platform.
+func deviceType(dev uint64) models.DeviceType {
+	return models.DeviceUnknown
+}
@@ -0,0 +1,18 @@
+//go:build !windows
+
+package scanner
+
+import (
+	"os"
+	"syscall"
+)
+
+// fileID returns the device and inode numbers backing info, or zeros if
+// unavailable. Paths with the same pair are hard links to the same data.
+func fileID(info os.FileInfo) (dev, ino uint64) {
+	st, ok := info.Sys().(*syscall.Stat_t)
+	if !ok {
+		return 0, 0
+	}
+	return uint64(st.Dev), uint64(st.Ino)
+}
@@ -0,0 +1,12 @@
+//go:build windows
+
+package scanner
+
+import "os"
+
+// fileID returns zeros on Windows: directory entries do not expose a file
+// index without opening a handle, so hard links are treated as separate files
+// and cache entries are keyed by path, size and mtime only.
+func fileID(info os.FileInfo) (dev, ino uint64) {
+	return 0, 0
+}
@@ -1,23 +1,96 @@
 package scanner
 
 import (
+	"sort"
+
 	"folder-cleaner-go/models"
 )
 
-// GroupBySize groups files that share the same size.
-// Files with unique sizes cannot be duplicates and are discarded.
-func GroupBySize(files []models.FileInfo) map[int64][]models.FileInfo {
-	groups := make(map[int64][]models.FileInfo)
-	for _, f := range files {
-		groups[f.Size] = append(groups[f.Size], f)
+// sizeIndex groups files by size as the walk streams them in. Files with a
+// size seen only once cannot be duplicates, so only the size is kept for
+// them, and memory grows with the number of candidates plus one integer per
+// distinct size. The first file of a size that turns up again is therefore
+// not known; such sizes are listed by unresolved, and the files are found by
+// a second walk passed to resolve.
+type sizeIndex struct {
+	once    map[int64]struct{}
+	multi   map[int64][]models.FileInfo
+	missing map[int64]struct{} // sizes in multi still lacking their first file
+}
+
+func newSizeIndex() *sizeIndex {
+	return &sizeIndex{
+		once:    make(map[int64]struct{}),
+		multi:   make(map[int64][]models.FileInfo),
+		missing: make(map[int64]struct{}),
 	}
-	// Remove groups with only one file
-	for size, group := range groups {
-		if len(group) < 2 {
-			delete(groups, size)
+}
+
+// add records a walked file.
+func (x *sizeIndex) add(f models.FileInfo) {
+	if files, ok := x.multi[f.Size]; ok {
+		x.multi[f.Size] = append(files, f)
+		return
+	}
+	if _, ok := x.once[f.Size]; !ok {
+		x.once[f.Size] = struct{}{}
+		return
+	}
+	delete(x.once, f.Size)
+	x.multi[f.Size] = []models.FileInfo{f}
+	x.missing[f.Size] = struct{}{}
+}
+
+// unresolved reports whether some sizes still lack their first file.
+func (x *sizeIndex) unresolved() bool {
+	return len(x.missing) > 0
+}
+
+// resolver returns a function to pass every file of a second walk to. It
+// adds the files of a size lacking its first file that are not recorded yet.
+// A first file that changed size or went away since the first walk is not
+// found again, and is simply left out.
+func (x *sizeIndex) resolver() func(models.FileInfo) {
+	known := make(map[string]struct{})
+	for size := range x.missing {
+		for _, f := range x.multi[size] {
+			known[f.Path] = struct{}{}
 		}
 	}
-	return groups
+	return func(f models.FileInfo) {
+		if _, ok := x.missing[f.Size]; !ok {
+			return
+		}
+		if _, ok := known[f.Path]; ok {
+			return
+		}
+		known[f.Path] = struct{}{}
+		x.multi[f.Size] = append(x.multi[f.Size], f)
+	}
+}
+
+// groups returns the files of each size shared by at least two different
+// inodes, largest size first. Hard links are collapsed as by
+// collapseHardLinks, and the extra paths returned keyed by inode.
+func (x *sizeIndex) groups() ([][]models.FileInfo, map[inodeKey][]models.FileInfo) {
+	sizes := make([]int64, 0, len(x.multi))
+	for size := range x.multi {
+		sizes = append(sizes, size)
+	}
+	sort.Slice(sizes, func(i, j int) bool { return sizes[i] > sizes[j] })
+
+	var groups [][]models.FileInfo
+	links := make(map[inodeKey][]models.FileInfo)
+	for _, size := range sizes {
+		files, l := collapseHardLinks(x.multi[size])
+		for k, paths := range l {
+			links[k] = paths
+		}
+		if len(files) >= 2 {
+			groups = append(groups, files)
+		}
+	}
+	return groups, links
 }
 
 // GroupByHash groups files that share the same hash value.
@@ -0,0 +1,52 @@
+package scanner
+
+import (
+	"reflect"
+	"sort"
+	"testing"
+
+	"folder-cleaner-go/models"
+)
+
+func TestSizeIndexResolve(t *testing.T) {
+	walked := []models.FileInfo{
+		{Path: "a1", Size: 10, Inode: 1},
+		{Path: "b", Size: 20, Inode: 2},
+		{Path: "a2", Size: 10, Inode: 3},
+		{Path: "c1", Size: 30, Inode: 4},
+		{Path: "a3", Size: 10, Inode: 5},
+		{Path: "c2", Size: 30, Inode: 6},
+	}
+	x := newSizeIndex()
+	for _, f := range walked {
+		x.add(f)
+	}
+	if len(x.once) != 1 {
+		t.Errorf("sizes seen once = %d, want 1", len(x.once))
+	}
+	if !x.unresolved() {
+		t.Fatal("unresolved() = false before the second walk")
+	}
+
+	// The second walk sees every file again, in a different order, and c1
+	// has gone in between.
+	resolve := x.resolver()
+	for _, i := range []int{5, 4, 2, 1, 0} {
+		resolve(walked[i])
+	}
+
+	groups, _ := x.groups()
+	var got [][]string
+	for _, files := range groups {
+		var paths []string
+		for _, f := range files {
+			paths = append(paths, f.Path)
+		}
+		sort.Strings(paths)
+		got = append(got, paths)
+	}
+	want := [][]string{{"a1", "a2", "a3"}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("groups = %v, want %v", got, want)
+	}
+}
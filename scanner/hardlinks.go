@@ -0,0 +1,58 @@
+package scanner
+
+import (
+	"folder-cleaner-go/models"
+)
+
+// inodeKey identifies the data behind a path; hard links share a key.
+type inodeKey struct {
+	dev, ino uint64
+}
+
+// collapseHardLinks keeps one path per inode so that hard links are hashed
+// once and never reported as duplicates of each other. The extra paths are
+// returned keyed by inode so they can be restored into the final groups.
+func collapseHardLinks(files []models.FileInfo) ([]models.FileInfo, map[inodeKey][]models.FileInfo) {
+	links := make(map[inodeKey][]models.FileInfo)
+	seen := make(map[inodeKey]bool, len(files))
+
+	unique := files[:0:0]
+	for _, f := range files {
+		if f.Inode == 0 {
+			unique = append(unique, f)
+			continue
+		}
+		k := inodeKey{f.Device, f.Inode}
+		if seen[k] {
+			links[k] = append(links[k], f)
+			continue
+		}
+		seen[k] = true
+		unique = append(unique, f)
+	}
+	return unique, links
+}
+
+// expandHardLinks adds the hard links removed by collapseHardLinks back into
+// each group next to the file they share an inode with, copying its hashes,
+// and recomputes the group sizes so links do not count as wasted space.
+func expandHardLinks(groups []models.DuplicateGroup, links map[inodeKey][]models.FileInfo) {
+	for i := range groups {
+		g := &groups[i]
+		if len(links) > 0 {
+			var files []models.FileInfo
+			for _, f := range g.Files {
+				files = append(files, f)
+				for _, l := range links[inodeKey{f.Device, f.Inode}] {
+					l.PartialHash = f.PartialHash
+					l.FullHash = f.FullHash
+					l.PerceptualHash = f.PerceptualHash
+					l.Distance, l.Similarity, l.Transform = f.Distance, f.Similarity, f.Transform
+					files = append(files, l)
+				}
+			}
+			g.Files = files
+		}
+		g.Recalculate()
+	}
+}
@@ -0,0 +1,283 @@
+package scanner
+
+import (
+	"context"
+	"math/bits"
+	"runtime"
+	"sort"
+
+	"golang.org/x/sync/errgroup"
+)
+
+// blocksPerWord is the number of 16-bit blocks each 64-bit word of a hash is
+// split into for multi-index hashing.
+const blocksPerWord = 4
+
+// neighbour is another image within the search distance.
+type neighbour struct {
+	index    int32
+	distance uint16
+}
+
+// hashIndex is a multi-index hash table over perceptual hashes of one or more
+// 64-bit words. Each hash is split into 16-bit blocks with one table per
+// block. By the pigeonhole principle, two hashes within distance d have at
+// least one block within about d/blocks of each other (see blockRadius), so a
+// query only probes the buckets near its own blocks instead of comparing
+// against every hash.
+type hashIndex struct {
+	words  int
+	blocks int
+	hashes []uint64 // words per hash, stored contiguously
+	// Per block, entries sorted by block value and then index;
+	// offsets[b][v]..offsets[b][v+1] is the range whose block equals v.
+	offsets [][]int32
+	entries [][]indexEntry
+}
+
+// indexEntry keeps the word holding the block beside its index. For 64-bit
+// hashes that is the whole hash, so candidates are checked without another
+// memory lookup; for longer hashes it gives a cheap lower bound.
+type indexEntry struct {
+	word  uint64
+	index int32
+}
+
+func (x *hashIndex) hash(i int) []uint64 {
+	return x.hashes[i*x.words : (i+1)*x.words]
+}
+
+func block(h []uint64, b int) uint16 {
+	return uint16(h[b/blocksPerWord] >> (16 * (b % blocksPerWord)))
+}
+
+// newHashIndex indexes hashes of the given number of words each, stored
+// contiguously.
+func newHashIndex(hashes []uint64, words int) *hashIndex {
+	n := len(hashes) / words
+	x := &hashIndex{
+		words:   words,
+		blocks:  words * blocksPerWord,
+		hashes:  hashes,
+		offsets: make([][]int32, words*blocksPerWord),
+		entries: make([][]indexEntry, words*blocksPerWord),
+	}
+	for b := 0; b < x.blocks; b++ {
+		offsets := make([]int32, 1<<16+1)
+		for i := 0; i < n; i++ {
+			offsets[int(block(x.hash(i), b))+1]++
+		}
+		for v := 1; v < len(offsets); v++ {
+			offsets[v] += offsets[v-1]
+		}
+		entries := make([]indexEntry, n)
+		next := append([]int32(nil), offsets[:1<<16]...)
+		for i := 0; i < n; i++ {
+			h := x.hash(i)
+			v := block(h, b)
+			entries[next[v]] = indexEntry{word: h[b/blocksPerWord], index: int32(i)}
+			next[v]++
+		}
+		x.offsets[b], x.entries[b] = offsets, entries
+	}
+	return x
+}
+
+// blockRadius returns how far block b of blocks must be searched for a total
+// distance. With distance = blocks*r + a, two hashes within distance either
+// match one of the first a+1 blocks within r or one of the others within r-1;
+// otherwise they would differ by at least distance+1 bits.
+func blockRadius(b, blocks, distance int) int {
+	r, a := distance/blocks, distance%blocks
+	if b <= a {
+		return r
+	}
+	return r - 1
+}
+
+// blockMasks returns every 16-bit mask with at most r bits set, ordered by
+// bit count so the masks for any smaller radius are a prefix.
+func blockMasks(r int) []uint16 {
+	var masks []uint16
+	for k := 0; k <= r; k++ {
+		for m := 0; m < 1<<16; m++ {
+			if bits.OnesCount16(uint16(m)) == k {
+				masks = append(masks, uint16(m))
+			}
+		}
+	}
+	return masks
+}
+
+// masksWithin returns the prefix of masks with at most r bits set.
+func masksWithin(masks []uint16, r int) []uint16 {
+	n := 0
+	for n < len(masks) && bits.OnesCount16(masks[n]) <= r {
+		n++
+	}
+	return masks[:n]
+}
+
+// search returns every hash with an index above after that is within
+// distance of h, in ascending index order.
+func (x *hashIndex) search(h []uint64, after, distance int, masks []uint16) []neighbour {
+	var out []neighbour
+	var single [1]uint64
+
+	for b := 0; b < x.blocks; b++ {
+		v := block(h, b)
+		hw := h[b/blocksPerWord]
+		for _, m := range masksWithin(masks, blockRadius(b, x.blocks, distance)) {
+			w := v ^ m
+			bucket := x.entries[b][x.offsets[b][w]:x.offsets[b][int(w)+1]]
+			// Buckets are in index order; only later hashes are reported
+			first := sort.Search(len(bucket), func(k int) bool { return int(bucket[k].index) > after })
+			for _, e := range bucket[first:] {
+				d := bits.OnesCount64(hw ^ e.word)
+				if d > distance {
+					continue
+				}
+				var o []uint64
+				if x.words == 1 {
+					single[0] = e.word
+					o = single[:]
+				} else {
+					o = x.hash(int(e.index))
+					if d = hashDistance(h, o); d > distance {
+						continue
+					}
+				}
+				// Report each pair once, from the first block that matched
+				seen := false
+				for p := 0; p < b; p++ {
+					if bits.OnesCount16(block(h, p)^block(o, p)) <= blockRadius(p, x.blocks, distance) {
+						seen = true
+						break
+					}
+				}
+				if !seen {
+					out = append(out, neighbour{index: e.index, distance: uint16(d)})
+				}
+			}
+		}
+	}
+
+	sort.Slice(out, func(a, b int) bool { return out[a].index < out[b].index })
+	return out
+}
+
+// findNeighbours returns, for each hash, the later hashes within distance.
+// hashes holds words per hash, stored contiguously. variants, if any, hold the
+// hashes of each image's rotated and mirrored versions in the same layout; a
+// pair's distance is then the smallest over all of them (see
+// variantDistance). Queries run in parallel and stop early when ctx is
+// cancelled.
+func findNeighbours(ctx context.Context, hashes []uint64, variants [][]uint64, words, distance int) ([][]neighbour, error) {
+	x := newHashIndex(hashes, words)
+	masks := blockMasks(distance / x.blocks)
+	n := len(hashes) / words
+	result := make([][]neighbour, n)
+
+	const chunk = 1024
+	g, ctx := errgroup.WithContext(ctx)
+	g.SetLimit(runtime.NumCPU())
+	for lo := 0; lo < n; lo += chunk {
+		hi := min(lo+chunk, n)
+		g.Go(func() error {
+			for i := lo; i < hi; i++ {
+				if err := ctx.Err(); err != nil {
+					return err
+				}
+				ns := x.search(x.hash(i), i, distance, masks)
+				if len(variants) > 0 {
+					ns = x.searchVariants(i, ns, variants, distance, masks)
+				}
+				if len(ns) > 0 {
+					result[i] = ns
+				}
+			}
+			return nil
+		})
+	}
+	if err := g.Wait(); err != nil {
+		return nil, err
+	}
+	if len(variants) > 0 {
+		result = mirrorNeighbours(result)
+	}
+	return result, nil
+}
+
+// searchVariants adds to found, image i's later neighbours, every image
+// within distance of one of i's variants. Those may come before i, as the
+// match is only found from i's side.
+func (x *hashIndex) searchVariants(i int, found []neighbour, variants [][]uint64, distance int, masks []uint16) []neighbour {
+	candidates := make(map[int32]bool, len(found))
+	for _, n := range found {
+		candidates[n.index] = true
+	}
+	for _, v := range variants {
+		for _, n := range x.search(v[i*x.words:(i+1)*x.words], -1, distance, masks) {
+			if int(n.index) != i {
+				candidates[n.index] = true
+			}
+		}
+	}
+
+	out := make([]neighbour, 0, len(candidates))
+	for j := range candidates {
+		// The identity distance may be beaten by a variant
+		if d := x.variantDistance(i, int(j), variants); d <= distance {
+			out = append(out, neighbour{index: j, distance: uint16(d)})
+		}
+	}
+	sort.Slice(out, func(a, b int) bool { return out[a].index < out[b].index })
+	return out
+}
+
+// variantDistance is the smallest distance between images i and j when
+// either may be replaced by one of its variants.
+func (x *hashIndex) variantDistance(i, j int, variants [][]uint64) int {
+	hi, hj := x.hash(i), x.hash(j)
+	d := hashDistance(hi, hj)
+	for _, v := range variants {
+		vi, vj := v[i*x.words:(i+1)*x.words], v[j*x.words:(j+1)*x.words]
+		d = min(d, hashDistance(vi, hj), hashDistance(hi, vj))
+	}
+	return d
+}
+
+// mirrorNeighbours moves each pair to the list of its lower index, dropping
+// pairs found from both sides, so every list again holds only later images.
+func mirrorNeighbours(found [][]neighbour) [][]neighbour {
+	out := make([][]neighbour, len(found))
+	for i, ns := range found {
+		for _, n := range ns {
+			if int(n.index) > i {
+				out[i] = append(out[i], n)
+			} else {
+				out[n.index] = append(out[n.index], neighbour{index: int32(i), distance: n.distance})
+			}
+		}
+	}
+	for i, ns := range out {
+		sort.Slice(ns, func(a, b int) bool { return ns[a].index < ns[b].index })
+		kept := ns[:0]
+		for k, n := range ns {
+			if k == 0 || n.index != ns[k-1].index {
+				kept = append(kept, n)
+			}
+		}
+		out[i] = kept
+	}
+	return out
+}
+
+// hashDistance is the Hamming distance between two hashes of equal length.
+func hashDistance(a, b []uint64) int {
+	d := 0
+	for i := range a {
+		d += bits.OnesCount64(a[i] ^ b[i])
+	}
+	return d
+}
@@ -0,0 +1,129 @@
+package scanner
+
+import (
+	"context"
+	"math/rand"
+	"reflect"
+	"testing"
+)
+
+// randomHashes returns n hashes of words each, stored contiguously. Most are
+// near-copies of a few bases with up to spread bits flipped, so every search
+// distance has both matches and near misses.
+func randomHashes(rng *rand.Rand, n, words, spread int) []uint64 {
+	bases := make([][]uint64, n/8+1)
+	for i := range bases {
+		bases[i] = make([]uint64, words)
+		for w := range bases[i] {
+			bases[i][w] = rng.Uint64()
+		}
+	}
+	hashes := make([]uint64, 0, n*words)
+	for i := 0; i < n; i++ {
+		h := append([]uint64(nil), bases[rng.Intn(len(bases))]...)
+		for k := rng.Intn(spread + 1); k > 0; k-- {
+			bit := rng.Intn(64 * words)
+			h[bit/64] ^= 1 << (bit % 64)
+		}
+		hashes = append(hashes, h...)
+	}
+	return hashes
+}
+
+// bruteNeighbours compares every pair, taking the smallest distance over the
+// variants of either image as findNeighbours does.
+func bruteNeighbours(hashes []uint64, variants [][]uint64, words, distance int) [][]neighbour {
+	n := len(hashes) / words
+	at := func(hs []uint64, i int) []uint64 { return hs[i*words : (i+1)*words] }
+	out := make([][]neighbour, n)
+	for i := 0; i < n; i++ {
+		for j := i + 1; j < n; j++ {
+			d := hashDistance(at(hashes, i), at(hashes, j))
+			for _, v := range variants {
+				d = min(d, hashDistance(at(v, i), at(hashes, j)), hashDistance(at(hashes, i), at(v, j)))
+			}
+			if d <= distance {
+				out[i] = append(out[i], neighbour{index: int32(j), distance: uint16(d)})
+			}
+		}
+	}
+	return out
+}
+
+func TestFindNeighboursMatchesBruteForce(t *testing.T) {
+	tests := []struct {
+		name     string
+		words    int
+		distance int
+		variants int
+	}{
+		{"64-bit exact", 1, 0, 0},
+		{"64-bit below one per block", 1, 3, 0},
+		{"64-bit", 1, 10, 0},
+		{"64-bit wide", 1, 20, 0},
+		{"256-bit", 4, 12, 0},
+		{"256-bit wide", 4, 40, 0},
+		{"64-bit with variants", 1, 10, 2},
+		{"256-bit with variants", 4, 30, 3},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rng := rand.New(rand.NewSource(int64(tt.words*1000 + tt.distance*10 + tt.variants)))
+			const n = 400
+			hashes := randomHashes(rng, n, tt.words, tt.distance+tt.words*4)
+			var variants [][]uint64
+			for v := 0; v < tt.variants; v++ {
+				// Half the images get a variant close to another image's hash
+				vh := randomHashes(rng, n, tt.words, tt.distance+4)
+				for i := 0; i < n; i += 2 {
+					copy(vh[i*tt.words:(i+1)*tt.words], hashes[rng.Intn(n)*tt.words:][:tt.words])
+				}
+				variants = append(variants, vh)
+			}
+
+			got, err := findNeighbours(context.Background(), hashes, variants, tt.words, tt.distance)
+			if err != nil {
+				t.Fatal(err)
+			}
+			want := bruteNeighbours(hashes, variants, tt.words, tt.distance)
+			pairs := 0
+			for i := range want {
+				pairs += len(want[i])
+				if len(got[i]) == 0 && len(want[i]) == 0 {
+					continue
+				}
+				if !reflect.DeepEqual(got[i], want[i]) {
+					t.Fatalf("neighbours of %d = %v, want %v", i, got[i], want[i])
+				}
+			}
+			if pairs == 0 {
+				t.Fatal("test data has no pairs within distance")
+			}
+		})
+	}
+}
+
+func TestFindNeighboursCancelled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	hashes := randomHashes(rand.New(rand.NewSource(1)), 10, 1, 4)
+	if _, err := findNeighbours(ctx, hashes, nil, 1, 4); err == nil {
+		t.Fatal("expected an error from a cancelled context")
+	}
+}
+
+func TestBlockRadiusCoversDistance(t *testing.T) {
+	// A pair missing every block differs by at least the sum of radius+1
+	// over the blocks, which must be just beyond the distance.
+	for _, blocks := range []int{4, 16} {
+		for distance := 0; distance <= 3*blocks; distance++ {
+			sum := 0
+			for b := 0; b < blocks; b++ {
+				sum += blockRadius(b, blocks, distance) + 1
+			}
+			if sum != distance+1 {
+				t.Errorf("blocks=%d distance=%d: radii cover %d bits, want %d", blocks, distance, sum, distance+1)
+			}
+		}
+	}
+}
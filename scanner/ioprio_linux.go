@@ -0,0 +1,42 @@
+//go:build linux
+
+package scanner
+
+import (
+	"runtime"
+
+	"golang.org/x/sys/unix"
+)
+
+// I/O priority values from linux/ioprio.h.
+const (
+	ioprioWhoProcess = 1 // a thread ID, or 0 for the calling thread
+	ioprioClassShift = 13
+	ioprioClassIdle  = 3
+)
+
+// idleIOPriority moves the calling goroutine to the idle I/O class, so its
+// reads are only served when the disk is otherwise unused, and returns a
+// function that restores the previous priority. I/O priority is per thread,
+// so the goroutine is locked to its thread until then. The idle class only
+// has an effect under the BFQ and CFQ I/O schedulers.
+func idleIOPriority() (restore func()) {
+	runtime.LockOSThread()
+	old, _, errno := unix.Syscall(unix.SYS_IOPRIO_GET, ioprioWhoProcess, 0, 0)
+	if errno != 0 {
+		runtime.UnlockOSThread()
+		return func() {}
+	}
+	_, _, errno = unix.Syscall(unix.SYS_IOPRIO_SET, ioprioWhoProcess, 0, ioprioClassIdle<<ioprioClassShift)
+	if errno != 0 {
+		runtime.UnlockOSThread()
+		return func() {}
+	}
+	return func() {
+		// If the old priority cannot be put back, the thread stays locked
+		// and is discarded when the goroutine exits.
+		if _, _, errno := unix.Syscall(unix.SYS_IOPRIO_SET, ioprioWhoProcess, 0, old); errno == 0 {
+			runtime.UnlockOSThread()
+		}
+	}
+}
@@ -0,0 +1,9 @@
+//go:build !linux
+
+package scanner
+
+// idleIOPriority does nothing: this platform has no per-thread I/O priority
+// the scanner can use.
+func idleIOPriority() (restore func()) {
+	return func() {}
+}
@@ -0,0 +1,152 @@
+package scanner
+
+import (
+	"container/heap"
+	"sync"
+
+	"folder-cleaner-go/models"
+)
+
+// ioJob is one queued file read.
+type ioJob struct {
+	inode uint64
+	run   func(release func())
+}
+
+// jobHeap is a min-heap of jobs by inode.
+type jobHeap []ioJob
+
+func (h jobHeap) Len() int           { return len(h) }
+func (h jobHeap) Less(i, j int) bool { return h[i].inode < h[j].inode }
+func (h jobHeap) Swap(i, j int)      { h[i], h[j] = h[j], h[i] }
+func (h *jobHeap) Push(x any)        { *h = append(*h, x.(ioJob)) }
+func (h *jobHeap) Pop() any {
+	old := *h
+	j := old[len(old)-1]
+	*h = old[:len(old)-1]
+	return j
+}
+
+// deviceQueue holds the pending reads for one device. Rotational devices
+// serve them in ascending inode order, sweeping from the lowest inode to the
+// highest and then starting over (C-SCAN); inode order approximates on-disk
+// layout on most Unix filesystems, so seeks are mostly short and forward.
+// Other devices serve reads in arrival order.
+type deviceQueue struct {
+	rotational bool
+	limit      int
+	running    int
+	fifo       []ioJob
+	ahead      jobHeap // rotational: inodes at or past head
+	behind     jobHeap // rotational: inodes before head, for the next sweep
+	head       uint64
+}
+
+func (q *deviceQueue) push(j ioJob) {
+	switch {
+	case !q.rotational:
+		q.fifo = append(q.fifo, j)
+	case j.inode >= q.head:
+		heap.Push(&q.ahead, j)
+	default:
+		heap.Push(&q.behind, j)
+	}
+}
+
+func (q *deviceQueue) pop() (ioJob, bool) {
+	if !q.rotational {
+		if len(q.fifo) == 0 {
+			return ioJob{}, false
+		}
+		j := q.fifo[0]
+		q.fifo = q.fifo[1:]
+		return j, true
+	}
+	if len(q.ahead) == 0 {
+		q.ahead, q.behind = q.behind, q.ahead
+	}
+	if len(q.ahead) == 0 {
+		return ioJob{}, false
+	}
+	j := heap.Pop(&q.ahead).(ioJob)
+	q.head = j.inode
+	return j, true
+}
+
+// ioScheduler runs file reads with a concurrency limit per device (st_dev),
+// chosen by the device's type, so a spinning disk is not made to seek between
+// many readers while NVMe drives get deep queues. One scheduler is shared by
+// all hashing stages of a scan, so the limits cover their combined reads.
+// With idle set, workers run at idle I/O priority where the platform has one.
+type ioScheduler struct {
+	limits  models.IOLimits
+	idle    bool
+	mu      sync.Mutex
+	devices map[uint64]*deviceQueue
+}
+
+func newIOScheduler(limits models.IOLimits, idle bool) *ioScheduler {
+	return &ioScheduler{limits: limits, idle: idle, devices: make(map[uint64]*deviceQueue)}
+}
+
+// submit queues run, which reads the file with the given inode on device dev.
+// It never blocks: a worker is started if the device is below its limit, and
+// otherwise run waits for one of the device's workers to pick it up. run may
+// call release once it has finished reading, to hand the device's slot to the
+// next read while it carries on with work that needs no disk, such as
+// decoding an image.
+func (s *ioScheduler) submit(dev, inode uint64, run func(release func())) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	q := s.devices[dev]
+	if q == nil {
+		kind := deviceType(dev)
+		q = &deviceQueue{rotational: kind == models.DeviceRotational, limit: s.limits.Limit(kind)}
+		s.devices[dev] = q
+	}
+	q.push(ioJob{inode: inode, run: run})
+	s.startWorker(q)
+}
+
+// startWorker starts a worker for q if it is below its limit. Callers hold
+// s.mu.
+func (s *ioScheduler) startWorker(q *deviceQueue) {
+	if q.running < q.limit {
+		q.running++
+		go s.work(q)
+	}
+}
+
+// work runs queued jobs for q until none are left, or until a job releases
+// its slot, in which case another worker takes over.
+func (s *ioScheduler) work(q *deviceQueue) {
+	if s.idle {
+		defer idleIOPriority()()
+	}
+	for {
+		s.mu.Lock()
+		j, ok := q.pop()
+		if !ok {
+			q.running--
+			s.mu.Unlock()
+			return
+		}
+		s.mu.Unlock()
+
+		released := false
+		j.run(func() {
+			if released {
+				return
+			}
+			released = true
+			s.mu.Lock()
+			q.running--
+			s.startWorker(q)
+			s.mu.Unlock()
+		})
+		if released {
+			return
+		}
+	}
+}
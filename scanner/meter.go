@@ -0,0 +1,159 @@
+package scanner
+
+import (
+	"context"
+	"io"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"folder-cleaner-go/models"
+)
+
+const (
+	// progressInterval is how often progress is reported while hashing.
+	progressInterval = 250 * time.Millisecond
+	// throughputWindow is the span over which throughput is averaged.
+	throughputWindow = 5 * time.Second
+)
+
+// scanReads throttles and counts the reads made while hashing. A nil
+// *scanReads leaves reads untouched.
+type scanReads struct {
+	limiter *rateLimiter
+	meter   *progressMeter
+}
+
+// reader returns r with its reads throttled and counted.
+func (rd *scanReads) reader(ctx context.Context, r io.Reader) io.Reader {
+	if rd == nil {
+		return r
+	}
+	return &countingReader{r: rd.limiter.reader(ctx, r), meter: rd.meter}
+}
+
+type countingReader struct {
+	r     io.Reader
+	meter *progressMeter
+}
+
+func (c *countingReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	c.meter.done.Add(int64(n))
+	return n, err
+}
+
+// stageMeter counts the files given to one hashing stage and those done.
+type stageMeter struct {
+	name     string
+	queued   atomic.Int64
+	done     atomic.Int64
+	finished atomic.Bool
+}
+
+type meterSample struct {
+	at   time.Time
+	done int64
+}
+
+// progressMeter tracks the hashing stages of a scan and the bytes they read,
+// for progress reports. The byte total covers the files queued so far, so it
+// grows as files pass from one stage to the next.
+type progressMeter struct {
+	done    atomic.Int64
+	total   atomic.Int64
+	current atomic.Pointer[string]
+
+	mu      sync.Mutex
+	stages  []*stageMeter
+	samples []meterSample // recent byte counts, oldest first
+}
+
+// setStages registers the hashing stages in pipeline order, starting the
+// counts over.
+func (m *progressMeter) setStages(names ...string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.done.Store(0)
+	m.total.Store(0)
+	m.current.Store(nil)
+	m.stages, m.samples = m.stages[:0], nil
+	for _, name := range names {
+		m.stages = append(m.stages, &stageMeter{name: name})
+	}
+}
+
+// stage returns the meter for the named stage.
+func (m *progressMeter) stage(name string) *stageMeter {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, st := range m.stages {
+		if st.name == name {
+			return st
+		}
+	}
+	st := &stageMeter{name: name}
+	m.stages = append(m.stages, st)
+	return st
+}
+
+// front returns the first stage that has not finished, which is the one a
+// progress report names, or nil if all have.
+func (m *progressMeter) front() *stageMeter {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, st := range m.stages {
+		if !st.finished.Load() {
+			return st
+		}
+	}
+	return nil
+}
+
+// expect adds n bytes that a queued file will read.
+func (m *progressMeter) expect(n int64) {
+	m.total.Add(n)
+}
+
+// skip removes n expected bytes that will not be read, because the hash was
+// already known or the file could not be read.
+func (m *progressMeter) skip(n int64) {
+	m.total.Add(-n)
+}
+
+// begin notes that path is being read.
+func (m *progressMeter) begin(path string) {
+	m.current.Store(&path)
+}
+
+// fill sets the byte counts, throughput, ETA and current file of p.
+func (m *progressMeter) fill(p *models.ScanProgress, now time.Time) {
+	done, total := m.done.Load(), m.total.Load()
+	p.BytesDone, p.BytesTotal = min(done, total), total
+	if cur := m.current.Load(); cur != nil {
+		p.CurrentFile = *cur
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.samples = append(m.samples, meterSample{now, done})
+	// Keep the newest sample older than the window as the baseline
+	for len(m.samples) > 2 && now.Sub(m.samples[1].at) >= throughputWindow {
+		m.samples = m.samples[1:]
+	}
+	first := m.samples[0]
+	if elapsed := now.Sub(first.at).Seconds(); elapsed > 0 {
+		p.Throughput = float64(done-first.done) / elapsed
+	}
+	if p.Throughput > 0 && total > done {
+		p.ETA = float64(total-done) / p.Throughput
+	}
+}
+
+// readBytes is how much hashing f for kind reads.
+func readBytes(kind hashKind, f models.FileInfo) int64 {
+	if kind == hashPartial {
+		return min(f.Size, 2*partialChunkSize)
+	}
+	return f.Size
+}
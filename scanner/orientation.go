@@ -0,0 +1,191 @@
+package scanner
+
+import (
+	"bytes"
+	"encoding/binary"
+	"image"
+	"image/draw"
+
+	xdraw "golang.org/x/image/draw"
+)
+
+// thumbnailSize is the side of the square working image that perceptual
+// hashes are computed from. It is well above the largest size any of the
+// hash algorithms reduce to, and small enough that orienting and transforming
+// it is cheap.
+const thumbnailSize = 256
+
+// EXIF orientations (tag 0x0112) and the operation that makes each upright.
+const (
+	orientNormal     = 1
+	orientFlip       = 2 // mirror left to right
+	orientRotate180  = 3
+	orientFlipV      = 4 // mirror top to bottom
+	orientTranspose  = 5 // mirror along the top-left diagonal
+	orientRotate90   = 6 // rotate 90 clockwise
+	orientTransverse = 7 // mirror along the top-right diagonal
+	orientRotate270  = 8 // rotate 90 anticlockwise
+)
+
+// thumbnail scales img to a thumbnailSize square. Aspect ratio is not kept:
+// every hash algorithm squashes the image to a square anyway.
+func thumbnail(img image.Image) *image.RGBA {
+	dst := image.NewRGBA(image.Rect(0, 0, thumbnailSize, thumbnailSize))
+	xdraw.BiLinear.Scale(dst, dst.Bounds(), img, img.Bounds(), draw.Src, nil)
+	return dst
+}
+
+// orient applies EXIF orientation o to img, returning a new image. Unknown
+// orientations and orientNormal return img unchanged.
+func orient(img *image.RGBA, o int) *image.RGBA {
+	if o <= orientNormal || o > orientRotate270 {
+		return img
+	}
+	b := img.Bounds()
+	w, h := b.Dx(), b.Dy()
+	dw, dh := w, h
+	if o >= orientTranspose {
+		dw, dh = h, w
+	}
+
+	dst := image.NewRGBA(image.Rect(0, 0, dw, dh))
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			var dx, dy int
+			switch o {
+			case orientFlip:
+				dx, dy = w-1-x, y
+			case orientRotate180:
+				dx, dy = w-1-x, h-1-y
+			case orientFlipV:
+				dx, dy = x, h-1-y
+			case orientTranspose:
+				dx, dy = y, x
+			case orientRotate90:
+				dx, dy = h-1-y, x
+			case orientTransverse:
+				dx, dy = h-1-y, w-1-x
+			case orientRotate270:
+				dx, dy = y, w-1-x
+			}
+			si := img.PixOffset(b.Min.X+x, b.Min.Y+y)
+			di := dst.PixOffset(dx, dy)
+			copy(dst.Pix[di:di+4], img.Pix[si:si+4])
+		}
+	}
+	return dst
+}
+
+// exifOrientation returns the EXIF orientation stored in an encoded JPEG,
+// TIFF, PNG or WebP image, or orientNormal if there is none.
+func exifOrientation(data []byte) int {
+	switch {
+	case bytes.HasPrefix(data, []byte{0xFF, 0xD8}):
+		return jpegOrientation(data)
+	case bytes.HasPrefix(data, []byte("II*\x00")), bytes.HasPrefix(data, []byte("MM\x00*")):
+		return tiffOrientation(data)
+	case bytes.HasPrefix(data, []byte("\x89PNG\r\n\x1a\n")):
+		return pngOrientation(data)
+	case len(data) >= 12 && bytes.Equal(data[:4], []byte("RIFF")) && bytes.Equal(data[8:12], []byte("WEBP")):
+		return webpOrientation(data)
+	}
+	return orientNormal
+}
+
+// jpegOrientation reads the APP1 Exif segment of a JPEG.
+func jpegOrientation(data []byte) int {
+	for i := 2; i+4 <= len(data); {
+		if data[i] != 0xFF {
+			return orientNormal
+		}
+		marker := data[i+1]
+		if marker == 0xFF { // fill byte
+			i++
+			continue
+		}
+		if marker == 0xD9 || marker == 0xDA { // end of image, start of scan
+			return orientNormal
+		}
+		n := int(binary.BigEndian.Uint16(data[i+2:]))
+		if n < 2 || i+2+n > len(data) {
+			return orientNormal
+		}
+		seg := data[i+4 : i+2+n]
+		if marker == 0xE1 && bytes.HasPrefix(seg, []byte("Exif\x00\x00")) {
+			return tiffOrientation(seg[6:])
+		}
+		i += 2 + n
+	}
+	return orientNormal
+}
+
+// pngOrientation reads the eXIf chunk of a PNG.
+func pngOrientation(data []byte) int {
+	for i := 8; i+8 <= len(data); {
+		n := int(binary.BigEndian.Uint32(data[i:]))
+		typ := string(data[i+4 : i+8])
+		if n < 0 || i+8+n > len(data) || typ == "IDAT" || typ == "IEND" {
+			return orientNormal
+		}
+		if typ == "eXIf" {
+			return tiffOrientation(data[i+8 : i+8+n])
+		}
+		i += 12 + n // length, type, data, CRC
+	}
+	return orientNormal
+}
+
+// webpOrientation reads the EXIF chunk of an extended WebP file.
+func webpOrientation(data []byte) int {
+	for i := 12; i+8 <= len(data); {
+		n := int(binary.LittleEndian.Uint32(data[i+4:]))
+		if n < 0 || i+8+n > len(data) {
+			return orientNormal
+		}
+		if string(data[i:i+4]) == "EXIF" {
+			payload := data[i+8 : i+8+n]
+			// Some writers keep the JPEG-style header
+			payload = bytes.TrimPrefix(payload, []byte("Exif\x00\x00"))
+			return tiffOrientation(payload)
+		}
+		i += 8 + n + n%2 // chunks are padded to even sizes
+	}
+	return orientNormal
+}
+
+// tiffOrientation reads the orientation tag from the first IFD of a TIFF
+// structure, as embedded in EXIF.
+func tiffOrientation(b []byte) int {
+	if len(b) < 8 {
+		return orientNormal
+	}
+	var order binary.ByteOrder
+	switch string(b[:2]) {
+	case "II":
+		order = binary.LittleEndian
+	case "MM":
+		order = binary.BigEndian
+	default:
+		return orientNormal
+	}
+
+	ifd := int(order.Uint32(b[4:]))
+	if ifd < 8 || ifd+2 > len(b) {
+		return orientNormal
+	}
+	count := int(order.Uint16(b[ifd:]))
+	for k := 0; k < count; k++ {
+		e := ifd + 2 + 12*k
+		if e+12 > len(b) {
+			break
+		}
+		// Tag 0x0112 is a single SHORT stored in the value field
+		if order.Uint16(b[e:]) == 0x0112 && order.Uint16(b[e+2:]) == 3 {
+			if o := int(order.Uint16(b[e+8:])); o >= orientNormal && o <= orientRotate270 {
+				return o
+			}
+			break
+		}
+	}
+	return orientNormal
+}
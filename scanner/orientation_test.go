@@ -0,0 +1,246 @@
+package scanner
+
+import (
+	"bytes"
+	"encoding/binary"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"slices"
+	"testing"
+)
+
+// tiffWithOrientation builds a TIFF header and first IFD holding a Make tag
+// and then the orientation tag, in the given byte order.
+func tiffWithOrientation(order binary.ByteOrder, o int) []byte {
+	var b bytes.Buffer
+	if order == binary.LittleEndian {
+		b.WriteString("II")
+	} else {
+		b.WriteString("MM")
+	}
+	binary.Write(&b, order, uint16(42))
+	binary.Write(&b, order, uint32(8)) // first IFD
+	binary.Write(&b, order, uint16(2)) // entries
+	// Make: ASCII, 4 bytes stored in the value field
+	binary.Write(&b, order, uint16(0x010F))
+	binary.Write(&b, order, uint16(2))
+	binary.Write(&b, order, uint32(4))
+	b.WriteString("Cam\x00")
+	// Orientation: one SHORT, padded to the 4-byte value field
+	binary.Write(&b, order, uint16(0x0112))
+	binary.Write(&b, order, uint16(3))
+	binary.Write(&b, order, uint32(1))
+	binary.Write(&b, order, uint16(o))
+	binary.Write(&b, order, uint16(0))
+	binary.Write(&b, order, uint32(0)) // no next IFD
+	return b.Bytes()
+}
+
+// segment encodes a JPEG marker segment.
+func segment(marker byte, payload []byte) []byte {
+	s := []byte{0xFF, marker, 0, 0}
+	binary.BigEndian.PutUint16(s[2:], uint16(len(payload)+2))
+	return append(s, payload...)
+}
+
+// jpegWithSegments encodes a small JPEG and inserts segs straight after SOI.
+func jpegWithSegments(t *testing.T, segs ...[]byte) []byte {
+	t.Helper()
+	img := image.NewRGBA(image.Rect(0, 0, 8, 4))
+	for i := range img.Pix {
+		img.Pix[i] = byte(i * 7)
+	}
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, img, nil); err != nil {
+		t.Fatal(err)
+	}
+	enc := buf.Bytes()
+	out := append([]byte(nil), enc[:2]...)
+	for _, s := range segs {
+		out = append(out, s...)
+	}
+	out = append(out, enc[2:]...)
+	if _, err := jpeg.Decode(bytes.NewReader(out)); err != nil {
+		t.Fatalf("test JPEG does not decode: %v", err)
+	}
+	return out
+}
+
+func exifSegment(tiff []byte) []byte {
+	return segment(0xE1, append([]byte("Exif\x00\x00"), tiff...))
+}
+
+func TestExifOrientationJPEG(t *testing.T) {
+	orders := []struct {
+		name  string
+		order binary.ByteOrder
+	}{
+		{"little endian", binary.LittleEndian},
+		{"big endian", binary.BigEndian},
+	}
+	for _, bo := range orders {
+		for o := orientNormal; o <= orientRotate270; o++ {
+			data := jpegWithSegments(t, exifSegment(tiffWithOrientation(bo.order, o)))
+			if got := exifOrientation(data); got != o {
+				t.Errorf("%s orientation %d: got %d", bo.name, o, got)
+			}
+		}
+	}
+
+	// Segments before the Exif one are skipped
+	data := jpegWithSegments(t,
+		segment(0xFE, []byte("a comment")),
+		segment(0xE1, []byte("http://ns.adobe.com/xap/1.0/\x00")),
+		exifSegment(tiffWithOrientation(binary.BigEndian, orientRotate90)))
+	if got := exifOrientation(data); got != orientRotate90 {
+		t.Errorf("after other segments: got %d, want %d", got, orientRotate90)
+	}
+}
+
+func TestExifOrientationMalformed(t *testing.T) {
+	valid := tiffWithOrientation(binary.LittleEndian, orientRotate90)
+	patched := func(at int, v ...byte) []byte {
+		b := append([]byte(nil), valid...)
+		copy(b[at:], v)
+		return b
+	}
+
+	tests := []struct {
+		name string
+		data []byte
+	}{
+		{"no exif", jpegWithSegments(t)},
+		{"not an image", []byte("plain text")},
+		{"empty exif", jpegWithSegments(t, exifSegment(nil))},
+		{"bad byte order", jpegWithSegments(t, exifSegment(patched(0, 'X', 'X')))},
+		{"ifd offset past end", jpegWithSegments(t, exifSegment(patched(4, 0xFF, 0xFF, 0, 0)))},
+		{"ifd offset inside header", jpegWithSegments(t, exifSegment(patched(4, 2, 0, 0, 0)))},
+		{"no entries", jpegWithSegments(t, exifSegment(patched(8, 0, 0)))},
+		{"orientation out of range", jpegWithSegments(t, exifSegment(patched(30, 9, 0)))},
+		{"orientation zero", jpegWithSegments(t, exifSegment(patched(30, 0, 0)))},
+		{"orientation not a short", jpegWithSegments(t, exifSegment(patched(24, 4, 0)))},
+		{"truncated ifd", jpegWithSegments(t, exifSegment(valid[:28]))},
+		{"segment length past end", []byte{0xFF, 0xD8, 0xFF, 0xE1, 0x7F, 0xFF, 'E', 'x', 'i', 'f', 0, 0}},
+		{"segment length too short", []byte{0xFF, 0xD8, 0xFF, 0xE1, 0x00, 0x01, 'E', 'x', 'i', 'f', 0, 0}},
+		{"garbage after SOI", []byte{0xFF, 0xD8, 0x00, 0x01, 0x02, 0x03}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := exifOrientation(tt.data); got != orientNormal {
+				t.Errorf("got %d, want %d", got, orientNormal)
+			}
+		})
+	}
+}
+
+func TestExifOrientationPNGAndWebP(t *testing.T) {
+	tiff := tiffWithOrientation(binary.BigEndian, orientRotate180)
+
+	var png bytes.Buffer
+	png.WriteString("\x89PNG\r\n\x1a\n")
+	chunk := func(typ string, data []byte) {
+		binary.Write(&png, binary.BigEndian, uint32(len(data)))
+		png.WriteString(typ)
+		png.Write(data)
+		png.Write([]byte{0, 0, 0, 0}) // CRC is not checked
+	}
+	chunk("IHDR", make([]byte, 13))
+	chunk("eXIf", tiff)
+	chunk("IEND", nil)
+	if got := exifOrientation(png.Bytes()); got != orientRotate180 {
+		t.Errorf("PNG: got %d, want %d", got, orientRotate180)
+	}
+
+	var webp bytes.Buffer
+	webp.WriteString("RIFF\x00\x00\x00\x00WEBP")
+	webp.WriteString("VP8X")
+	binary.Write(&webp, binary.LittleEndian, uint32(10))
+	webp.Write(make([]byte, 10))
+	payload := append([]byte("Exif\x00\x00"), tiff...)
+	webp.WriteString("EXIF")
+	binary.Write(&webp, binary.LittleEndian, uint32(len(payload)))
+	webp.Write(payload)
+	if got := exifOrientation(webp.Bytes()); got != orientRotate180 {
+		t.Errorf("WebP: got %d, want %d", got, orientRotate180)
+	}
+}
+
+// labelled returns a 3x2 image whose pixels are labelled A-F row by row in
+// their red channel.
+func labelled() *image.RGBA {
+	img := image.NewRGBA(image.Rect(0, 0, 3, 2))
+	for y := 0; y < 2; y++ {
+		for x := 0; x < 3; x++ {
+			img.SetRGBA(x, y, color.RGBA{R: byte('A' + y*3 + x), A: 255})
+		}
+	}
+	return img
+}
+
+// labels reads an image's pixel labels back as rows.
+func labels(img *image.RGBA) []string {
+	b := img.Bounds()
+	var rows []string
+	for y := b.Min.Y; y < b.Max.Y; y++ {
+		var row []byte
+		for x := b.Min.X; x < b.Max.X; x++ {
+			row = append(row, img.RGBAAt(x, y).R)
+		}
+		rows = append(rows, string(row))
+	}
+	return rows
+}
+
+func TestOrient(t *testing.T) {
+	// How a stored image labelled ABC/DEF displays under each orientation.
+	tests := []struct {
+		o    int
+		want []string
+	}{
+		{orientNormal, []string{"ABC", "DEF"}},
+		{orientFlip, []string{"CBA", "FED"}},
+		{orientRotate180, []string{"FED", "CBA"}},
+		{orientFlipV, []string{"DEF", "ABC"}},
+		{orientTranspose, []string{"AD", "BE", "CF"}},
+		{orientRotate90, []string{"DA", "EB", "FC"}},
+		{orientTransverse, []string{"FC", "EB", "DA"}},
+		{orientRotate270, []string{"CF", "BE", "AD"}},
+	}
+	for _, tt := range tests {
+		got := labels(orient(labelled(), tt.o))
+		if !slices.Equal(got, tt.want) {
+			t.Errorf("orientation %d: got %v, want %v", tt.o, got, tt.want)
+		}
+	}
+}
+
+func TestOrientUnknownAndInverse(t *testing.T) {
+	img := labelled()
+	for _, o := range []int{0, 9, -1} {
+		if got := orient(img, o); got != img {
+			t.Errorf("orientation %d did not return the image unchanged", o)
+		}
+	}
+
+	inverse := map[int]int{
+		orientFlip:       orientFlip,
+		orientRotate180:  orientRotate180,
+		orientFlipV:      orientFlipV,
+		orientTranspose:  orientTranspose,
+		orientRotate90:   orientRotate270,
+		orientTransverse: orientTransverse,
+		orientRotate270:  orientRotate90,
+	}
+	for o, inv := range inverse {
+		if got := labels(orient(orient(img, o), inv)); !slices.Equal(got, labels(img)) {
+			t.Errorf("orientation %d then %d: got %v", o, inv, got)
+		}
+	}
+
+	// Images not anchored at the origin are read from their bounds
+	sub := img.SubImage(image.Rect(1, 0, 3, 2)).(*image.RGBA)
+	if got := labels(orient(sub, orientRotate90)); !slices.Equal(got, []string{"EB", "FC"}) {
+		t.Errorf("sub-image rotated: got %v", got)
+	}
+}
@@ -1,79 +1,184 @@
 package scanner
 
 import (
+	"bytes"
 	"context"
+	"fmt"
 	"image"
 	_ "image/gif"
 	_ "image/jpeg"
 	_ "image/png"
+	"io"
 	"os"
-	"runtime"
 	"strconv"
 	"strings"
 
 	"folder-cleaner-go/models"
 
 	"github.com/corona10/goimagehash"
-	"golang.org/x/sync/errgroup"
+	_ "golang.org/x/image/bmp"
+	_ "golang.org/x/image/tiff"
+	_ "golang.org/x/image/webp"
 )
 
+// imageExtensions lists the formats with a registered decoder, keyed like
+// FileInfo.Extension (lower case, no dot). HEIC and AVIF are not included:
+// there is no pure-Go decoder for them.
 var imageExtensions = map[string]bool{
-	".jpg": true, ".jpeg": true, ".png": true, ".gif": true,
-	".bmp": true, ".tiff": true, ".webp": true,
+	"jpg": true, "jpeg": true, "png": true, "gif": true,
+	"bmp": true, "tif": true, "tiff": true, "webp": true,
 }
 
-// PerceptualHash computes perceptual hashes for image files.
-func PerceptualHash(ctx context.Context, files []models.FileInfo) ([]models.FileInfo, error) {
-	g, ctx := errgroup.WithContext(ctx)
-	g.SetLimit(runtime.NumCPU())
+// isImage reports whether f has a format PerceptualHash can decode.
+func isImage(f models.FileInfo) bool {
+	return imageExtensions[strings.ToLower(f.Extension)]
+}
 
-	results := make([]models.FileInfo, len(files))
-	copy(results, files)
+// imageTransforms are the variants hashed when matching rotated and mirrored
+// copies, with the EXIF orientation operation that produces each.
+var imageTransforms = []struct {
+	transform   models.ImageTransform
+	orientation int
+}{
+	{models.TransformRotate90, orientRotate90},
+	{models.TransformRotate180, orientRotate180},
+	{models.TransformRotate270, orientRotate270},
+	{models.TransformFlip, orientFlip},
+}
 
-	for i := range results {
-		ext := strings.ToLower(results[i].Extension)
-		if !imageExtensions[ext] {
-			continue
-		}
+// readImage reads the whole image file at path through rd, for
+// computePerceptualHash.
+func readImage(ctx context.Context, rd *scanReads, path string) ([]byte, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return io.ReadAll(rd.reader(ctx, f))
+}
 
-		i := i
-		g.Go(func() error {
-			if ctx.Err() != nil {
-				return ctx.Err()
-			}
+// computePerceptualHash hashes the image read by readImage with algo, tagging
+// the hash with its algorithm (see formatPerceptualHash). Images are hashed
+// as displayed, after EXIF orientation. With transforms, the hashes of every
+// imageTransforms variant are included as well.
+func computePerceptualHash(data []byte, algo models.HashAlgorithm, transforms bool) (string, error) {
+	img, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return "", fmt.Errorf("%w: %v", errUndecodable, err)
+	}
+	thumb := orient(thumbnail(img), exifOrientation(data))
 
-			hash, err := computePerceptualHash(results[i].Path)
+	if algo != models.HashAverage && algo != models.HashDifference && algo != models.HashExtended {
+		algo = models.HashPerception
+	}
+	ph := perceptualHash{algo: algo}
+	if ph.words, err = hashImage(thumb, algo); err != nil {
+		return "", err
+	}
+	if transforms {
+		for _, t := range imageTransforms {
+			words, err := hashImage(orient(thumb, t.orientation), algo)
 			if err != nil {
-				// Skip files that can't be decoded
-				return nil
+				return "", err
 			}
-			results[i].PerceptualHash = hash
-			return nil
-		})
+			ph.variants = append(ph.variants, words)
+		}
 	}
+	return formatPerceptualHash(ph), nil
+}
 
-	if err := g.Wait(); err != nil {
+// hashImage computes the hash of img with algo as 64-bit words.
+func hashImage(img image.Image, algo models.HashAlgorithm) ([]uint64, error) {
+	if algo == models.HashExtended {
+		ext, err := goimagehash.ExtPerceptionHash(img, 16, 16)
+		if err != nil {
+			return nil, err
+		}
+		return ext.GetHash(), nil
+	}
+
+	var hash *goimagehash.ImageHash
+	var err error
+	switch algo {
+	case models.HashAverage:
+		hash, err = goimagehash.AverageHash(img)
+	case models.HashDifference:
+		hash, err = goimagehash.DifferenceHash(img)
+	default:
+		hash, err = goimagehash.PerceptionHash(img)
+	}
+	if err != nil {
 		return nil, err
 	}
-	return results, nil
+	return []uint64{hash.GetHash()}, nil
 }
 
-func computePerceptualHash(path string) (string, error) {
-	f, err := os.Open(path)
-	if err != nil {
-		return "", err
+// perceptualHash is a decoded perceptual hash: the image's own hash and,
+// when transform matching was on, one hash per imageTransforms variant.
+type perceptualHash struct {
+	algo     models.HashAlgorithm
+	words    []uint64
+	variants [][]uint64
+}
+
+// formatPerceptualHash encodes a hash as "algorithm:hex", with any variant
+// hashes appended as ",hex", so hashes from different algorithms are never
+// compared with each other.
+func formatPerceptualHash(ph perceptualHash) string {
+	var b strings.Builder
+	b.WriteString(string(ph.algo))
+	b.WriteByte(':')
+	writeWords(&b, ph.words)
+	for _, v := range ph.variants {
+		b.WriteByte(',')
+		writeWords(&b, v)
 	}
-	defer f.Close()
+	return b.String()
+}
 
-	img, _, err := image.Decode(f)
-	if err != nil {
-		return "", err
+func writeWords(b *strings.Builder, words []uint64) {
+	for _, w := range words {
+		fmt.Fprintf(b, "%016x", w)
 	}
+}
 
-	hash, err := goimagehash.PerceptionHash(img)
-	if err != nil {
-		return "", err
+// parsePerceptualHash decodes a hash written by formatPerceptualHash. Every
+// hash in it must have the algorithm's length.
+func parsePerceptualHash(s string) (perceptualHash, bool) {
+	algo, rest, ok := strings.Cut(s, ":")
+	if !ok {
+		return perceptualHash{}, false
+	}
+	ph := perceptualHash{algo: models.HashAlgorithm(algo)}
+	parts := strings.Split(rest, ",")
+	if len(parts) != 1 && len(parts) != len(imageTransforms)+1 {
+		return perceptualHash{}, false
+	}
+	for i, part := range parts {
+		words, ok := parseWords(part, ph.algo.HashBits()/64)
+		if !ok {
+			return perceptualHash{}, false
+		}
+		if i == 0 {
+			ph.words = words
+		} else {
+			ph.variants = append(ph.variants, words)
+		}
 	}
+	return ph, true
+}
 
-	return strconv.FormatUint(hash.GetHash(), 16), nil
+func parseWords(digits string, n int) ([]uint64, bool) {
+	if len(digits) != 16*n {
+		return nil, false
+	}
+	words := make([]uint64, n)
+	for i := range words {
+		w, err := strconv.ParseUint(digits[16*i:16*(i+1)], 16, 64)
+		if err != nil {
+			return nil, false
+		}
+		words[i] = w
+	}
+	return words, true
 }
@@ -0,0 +1,135 @@
+package scanner
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+
+	"folder-cleaner-go/models"
+)
+
+// hashStage is one hashing step of the streaming pipeline.
+type hashStage struct {
+	name string // reported to the ProgressCallback
+	kind hashKind
+	// hash computes the hash of a file. It runs in a device slot of the
+	// scan's ioScheduler, and may call release once it is done reading.
+	hash func(f models.FileInfo, release func()) (string, error)
+	// regroup splits each group by the new hash, dropping files that end up
+	// alone or could not be hashed. Otherwise groups pass through unchanged.
+	regroup bool
+}
+
+// pendingGroup is a group whose files are still being hashed.
+type pendingGroup struct {
+	files     []models.FileInfo
+	remaining atomic.Int32
+}
+
+// finish returns the groups a fully hashed group becomes for the next stage.
+func (st hashStage) finish(files []models.FileInfo) [][]models.FileInfo {
+	if !st.regroup {
+		return [][]models.FileInfo{files}
+	}
+	groups := GroupByHash(files, func(f models.FileInfo) string {
+		return hashOf(f, st.kind)
+	})
+	out := make([][]models.FileInfo, 0, len(groups))
+	for _, g := range groups {
+		out = append(out, g)
+	}
+	return out
+}
+
+// runStage hashes the files of each group received on in and sends the
+// resulting groups to out, closing it once in is drained. Reads go through
+// the scan's ioScheduler, so a large group is spread over all the workers its
+// device allows, and a group moves on as soon as its last file is done.
+// Groups are updated in place rather than copied.
+func (s *Scanner) runStage(ctx context.Context, st hashStage, in <-chan []models.FileInfo, out chan<- []models.FileInfo) error {
+	defer close(out)
+
+	var wg sync.WaitGroup
+	sm := s.meter.stage(st.name)
+	emit := func(files []models.FileInfo) {
+		for _, group := range st.finish(files) {
+			select {
+			case out <- group:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}
+
+	for files := range in {
+		if ctx.Err() != nil {
+			break
+		}
+		if len(files) == 0 {
+			continue
+		}
+
+		pg := &pendingGroup{files: files}
+		pg.remaining.Store(int32(len(files)))
+		for i := range files {
+			f := &files[i]
+			sm.queued.Add(1)
+			s.meter.expect(readBytes(st.kind, *f))
+			wg.Add(1)
+			s.io.submit(f.Device, f.Inode, func(release func()) {
+				defer wg.Done()
+				if ctx.Err() != nil {
+					return
+				}
+				s.hashFile(st, f, release)
+				sm.done.Add(1)
+				if pg.remaining.Add(-1) == 0 {
+					emit(pg.files)
+				}
+			})
+		}
+	}
+	wg.Wait()
+
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	sm.finished.Store(true)
+	n := int(sm.done.Load())
+	s.progress(st.name, n, n)
+	s.ckpt.finishStage(st.name)
+	return nil
+}
+
+// hashFile fills in f's hash for st from the cache, or computes and caches
+// it, and records it in the checkpoint. Files restored from a checkpoint with
+// the hash already set are left as they are, and files that cannot be read
+// are left without one and added to the skipped list.
+func (s *Scanner) hashFile(st hashStage, f *models.FileInfo, release func()) {
+	if hashOf(*f, st.kind) != "" {
+		s.meter.skip(readBytes(st.kind, *f))
+		return
+	}
+	h := s.cache.get(*f, st.kind, s.hashAlgorithm(), s.settings.MatchTransforms)
+	cached := h != ""
+	if cached {
+		s.meter.skip(readBytes(st.kind, *f))
+	} else {
+		s.meter.begin(f.Path)
+		var err error
+		if h, err = st.hash(*f, release); err != nil {
+			s.meter.skip(readBytes(st.kind, *f))
+			s.skipped.add(f.Path, st.name, err)
+			if errors.Is(err, errUndecodable) {
+				s.undecoded.Add(1)
+			}
+			return
+		}
+	}
+	setHash(f, st.kind, h)
+	if !cached {
+		s.cache.put(*f, st.kind)
+	}
+	s.ckpt.record(f.Path, st.kind, h)
+}
@@ -0,0 +1,172 @@
+package scanner
+
+import (
+	"context"
+	"io"
+	"sync"
+	"time"
+)
+
+// throttleChunk is the largest read made at once while a rate limit is set,
+// so the limit is met smoothly rather than in bursts of whole buffers.
+const throttleChunk = 64 * 1024
+
+// rateLimiter caps the combined read rate of a scan with a token bucket that
+// holds at most one second's worth of bytes. The rate can be changed while
+// reads are waiting; zero means unlimited. It can also be paused, holding
+// every read until it is resumed. A nil *rateLimiter never limits.
+type rateLimiter struct {
+	mu      sync.Mutex
+	rate    int64   // bytes per second
+	tokens  float64 // bytes that may be read now; negative while in debt
+	last    time.Time
+	paused  bool
+	changed chan struct{} // closed and replaced when the rate or pause changes
+}
+
+func newRateLimiter(rate int64) *rateLimiter {
+	return &rateLimiter{rate: max(rate, 0), last: time.Now(), changed: make(chan struct{})}
+}
+
+// setRate changes the limit, waking any reads waiting under the old one.
+func (l *rateLimiter) setRate(rate int64) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.refill(time.Now())
+	l.rate = max(rate, 0)
+	l.tokens = min(l.tokens, float64(l.rate))
+	if l.rate == 0 {
+		l.tokens = 0 // reads made while unlimited are not owed later
+	}
+	l.notify()
+}
+
+// setPaused pauses or resumes reads, waking any that are waiting.
+func (l *rateLimiter) setPaused(paused bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.paused = paused
+	l.notify()
+}
+
+// isPaused reports whether reads are paused.
+func (l *rateLimiter) isPaused() bool {
+	if l == nil {
+		return false
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.paused
+}
+
+// notify wakes the reads waiting on l. Callers hold l.mu.
+func (l *rateLimiter) notify() {
+	close(l.changed)
+	l.changed = make(chan struct{})
+}
+
+// hold blocks while l is paused, or until ctx is done.
+func (l *rateLimiter) hold(ctx context.Context) error {
+	if l == nil {
+		return nil
+	}
+	for {
+		l.mu.Lock()
+		paused, changed := l.paused, l.changed
+		l.mu.Unlock()
+		if !paused {
+			return nil
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-changed:
+		}
+	}
+}
+
+// limited reports whether a rate limit is set.
+func (l *rateLimiter) limited() bool {
+	if l == nil {
+		return false
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.rate > 0
+}
+
+// refill adds the tokens earned since the last refill. Callers hold l.mu.
+func (l *rateLimiter) refill(now time.Time) {
+	if l.rate > 0 {
+		l.tokens = min(l.tokens+now.Sub(l.last).Seconds()*float64(l.rate), float64(l.rate))
+	}
+	l.last = now
+}
+
+// wait blocks until n more bytes may be read, or ctx is done. A read is let
+// through whenever the bucket is not in debt and then charged in full, so
+// reads larger than the bucket still make progress.
+func (l *rateLimiter) wait(ctx context.Context, n int) error {
+	if l == nil {
+		return nil
+	}
+	for {
+		if err := l.hold(ctx); err != nil {
+			return err
+		}
+		l.mu.Lock()
+		if l.paused { // paused again since hold returned
+			l.mu.Unlock()
+			continue
+		}
+		if l.rate == 0 {
+			l.mu.Unlock()
+			return nil
+		}
+		l.refill(time.Now())
+		if l.tokens >= 0 {
+			l.tokens -= float64(n)
+			l.mu.Unlock()
+			return nil
+		}
+		delay := time.Duration(-l.tokens / float64(l.rate) * float64(time.Second))
+		changed := l.changed
+		l.mu.Unlock()
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		case <-changed:
+			timer.Stop()
+		case <-timer.C:
+		}
+	}
+}
+
+// reader returns r with its reads charged to l.
+func (l *rateLimiter) reader(ctx context.Context, r io.Reader) io.Reader {
+	if l == nil {
+		return r
+	}
+	return &throttledReader{ctx: ctx, r: r, lim: l}
+}
+
+type throttledReader struct {
+	ctx context.Context
+	r   io.Reader
+	lim *rateLimiter
+}
+
+func (t *throttledReader) Read(p []byte) (int, error) {
+	if len(p) > throttleChunk && t.lim.limited() {
+		p = p[:throttleChunk]
+	}
+	if err := t.lim.wait(t.ctx, len(p)); err != nil {
+		return 0, err
+	}
+	return t.r.Read(p)
+}
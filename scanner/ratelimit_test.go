@@ -0,0 +1,171 @@
+package scanner
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"testing"
+	"time"
+)
+
+// cancelled returns a context that is already done, so a call that would
+// block returns its error at once instead.
+func cancelled() context.Context {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	return ctx
+}
+
+// waitReturns runs wait in the background, returning a channel that receives
+// its result.
+func waitReturns(l *rateLimiter, n int) <-chan error {
+	done := make(chan error, 1)
+	go func() { done <- l.wait(context.Background(), n) }()
+	return done
+}
+
+func expectReturn(t *testing.T, done <-chan error) {
+	t.Helper()
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatalf("wait returned %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("wait was not woken")
+	}
+}
+
+func TestRateLimiterRefill(t *testing.T) {
+	l := newRateLimiter(1000)
+	t0 := l.last
+
+	l.refill(t0.Add(250 * time.Millisecond))
+	if l.tokens != 250 {
+		t.Errorf("after 250ms: %v tokens, want 250", l.tokens)
+	}
+	l.refill(t0.Add(10 * time.Second))
+	if l.tokens != 1000 {
+		t.Errorf("after 10s: %v tokens, want one second's worth", l.tokens)
+	}
+
+	l.setRate(100)
+	if l.tokens > 100 {
+		t.Errorf("after lowering the rate: %v tokens, want at most 100", l.tokens)
+	}
+}
+
+func TestRateLimiterChargesReads(t *testing.T) {
+	l := newRateLimiter(1000)
+
+	// An empty bucket is not in debt, so a read larger than it goes through
+	if err := l.wait(cancelled(), 4000); err != nil {
+		t.Fatalf("first read: %v", err)
+	}
+	l.mu.Lock()
+	tokens := l.tokens
+	l.mu.Unlock()
+	if tokens > -3000 {
+		t.Fatalf("%v tokens after reading 4000 bytes at 1000/s, want about -4000", tokens)
+	}
+
+	// Now in debt for seconds: the next read waits
+	if err := l.wait(cancelled(), 1); !errors.Is(err, context.Canceled) {
+		t.Fatalf("read in debt returned %v, want context.Canceled", err)
+	}
+}
+
+func TestRateLimiterSetRateWakesWaiters(t *testing.T) {
+	l := newRateLimiter(1)
+	if err := l.wait(context.Background(), 1000); err != nil {
+		t.Fatal(err)
+	}
+	// In debt for about 1000 seconds
+	done := waitReturns(l, 1)
+	l.setRate(0)
+	expectReturn(t, done)
+
+	// Reads made while unlimited are not owed once a limit is set again
+	l.setRate(1000)
+	if err := l.wait(cancelled(), 1); err != nil {
+		t.Errorf("read after lifting the limit: %v", err)
+	}
+}
+
+func TestRateLimiterPause(t *testing.T) {
+	for _, rate := range []int64{0, 1 << 30} {
+		l := newRateLimiter(rate)
+		l.setPaused(true)
+		if !l.isPaused() {
+			t.Fatal("isPaused is false after pausing")
+		}
+		if err := l.hold(cancelled()); !errors.Is(err, context.Canceled) {
+			t.Errorf("rate %d: hold while paused returned %v", rate, err)
+		}
+		if err := l.wait(cancelled(), 1); !errors.Is(err, context.Canceled) {
+			t.Errorf("rate %d: wait while paused returned %v", rate, err)
+		}
+
+		done := waitReturns(l, 1)
+		select {
+		case err := <-done:
+			t.Fatalf("rate %d: wait returned %v while paused", rate, err)
+		default:
+		}
+		l.setPaused(false)
+		expectReturn(t, done)
+		if l.isPaused() {
+			t.Error("isPaused is true after resuming")
+		}
+		if err := l.hold(cancelled()); err != nil {
+			t.Errorf("rate %d: hold after resuming returned %v", rate, err)
+		}
+	}
+}
+
+func TestNilRateLimiter(t *testing.T) {
+	var l *rateLimiter
+	if l.isPaused() || l.limited() {
+		t.Error("nil limiter reports a limit")
+	}
+	if err := l.hold(cancelled()); err != nil {
+		t.Errorf("hold: %v", err)
+	}
+	if err := l.wait(cancelled(), 1<<30); err != nil {
+		t.Errorf("wait: %v", err)
+	}
+	r := bytes.NewReader(nil)
+	if l.reader(context.Background(), r) != io.Reader(r) {
+		t.Error("nil limiter wrapped the reader")
+	}
+}
+
+func TestThrottledReaderChunks(t *testing.T) {
+	data := make([]byte, 3*throttleChunk)
+	for _, tt := range []struct {
+		rate int64
+		want int
+	}{
+		{0, len(data)},
+		{1 << 40, throttleChunk},
+	} {
+		l := newRateLimiter(tt.rate)
+		r := l.reader(context.Background(), bytes.NewReader(data))
+		n, err := r.Read(make([]byte, len(data)))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if n != tt.want {
+			t.Errorf("rate %d: read %d bytes at once, want %d", tt.rate, n, tt.want)
+		}
+	}
+
+	// A cancelled read returns the context's error without reading
+	l := newRateLimiter(1)
+	l.setPaused(true)
+	r := l.reader(cancelled(), bytes.NewReader(data))
+	if n, err := r.Read(make([]byte, 10)); n != 0 || !errors.Is(err, context.Canceled) {
+		t.Errorf("paused read returned %d, %v", n, err)
+	}
+}
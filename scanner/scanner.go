@@ -3,20 +3,35 @@ package scanner
 import (
 	"context"
 	"fmt"
-	"strconv"
+	"runtime"
+	"sync"
+	"sync/atomic"
+	"time"
 
 	"folder-cleaner-go/models"
 
 	"github.com/google/uuid"
+	"golang.org/x/sync/errgroup"
 )
 
 // ProgressCallback is called to report scan progress to the caller.
-type ProgressCallback func(stage string, processed, total int)
+type ProgressCallback func(p models.ScanProgress)
 
 // Scanner orchestrates the multi-stage deduplication pipeline.
 type Scanner struct {
 	settings   models.ScanSettings
 	onProgress ProgressCallback
+	cache      *HashCache
+	stats      models.ScanStats
+	progressMu sync.Mutex
+	last       models.ScanProgress // most recent report, guarded by progressMu
+	io         *ioScheduler
+	limiter    *rateLimiter
+	meter      *progressMeter
+	skipped    skipList
+	undecoded  atomic.Int64 // images that were read but could not be decoded
+	ckpt       *checkpoint
+	resume     bool
 }
 
 // New creates a new Scanner with the given settings.
@@ -24,211 +39,525 @@ func New(settings models.ScanSettings, onProgress ProgressCallback) *Scanner {
 	return &Scanner{
 		settings:   settings,
 		onProgress: onProgress,
+		limiter:    newRateLimiter(settings.MaxReadRate),
+		meter:      &progressMeter{},
 	}
 }
 
-// Run executes the full deduplication pipeline and returns duplicate groups.
-func (s *Scanner) Run(ctx context.Context) ([]models.DuplicateGroup, error) {
-	// Stage 1: Walk directories
-	s.onProgress("walking", 0, 0)
-	files, err := Walk(ctx, s.settings.Paths, s.settings.MinFileSizeBytes(), s.settings.ExcludedDirs, s.settings.SkipHidden)
-	if err != nil {
-		return nil, fmt.Errorf("walk: %w", err)
+// Stats returns counts from the last Run.
+func (s *Scanner) Stats() models.ScanStats {
+	return s.stats
+}
+
+// Skipped returns the files and directories the last Run could not examine,
+// with the reason for each, sorted by path.
+func (s *Scanner) Skipped() []models.SkippedFile {
+	return s.skipped.list()
+}
+
+// hashAlgorithm returns the configured perceptual hash, defaulting to pHash.
+func (s *Scanner) hashAlgorithm() models.HashAlgorithm {
+	if s.settings.HashAlgorithm == "" {
+		return models.HashPerception
 	}
-	s.onProgress("walking", len(files), len(files))
+	return s.settings.HashAlgorithm
+}
 
-	if err := ctx.Err(); err != nil {
-		return nil, err
+// Pause holds every read of the scan, and the walk, until Resume is called.
+// Files being hashed keep their progress, and the change is reported to the
+// ProgressCallback. It may be called at any time, including before Run.
+func (s *Scanner) Pause() {
+	s.setPaused(true)
+}
+
+// Resume continues a paused scan.
+func (s *Scanner) Resume() {
+	s.setPaused(false)
+}
+
+// Paused reports whether the scan is paused.
+func (s *Scanner) Paused() bool {
+	return s.limiter.isPaused()
+}
+
+func (s *Scanner) setPaused(paused bool) {
+	s.progressMu.Lock()
+	defer s.progressMu.Unlock()
+	if s.limiter.isPaused() == paused {
+		return
+	}
+	s.limiter.setPaused(paused)
+	s.last.Paused = paused
+	if s.last.Stage != "" {
+		s.onProgress(s.last)
 	}
+}
+
+// SetCheckpoint makes Run save its progress in dir periodically, so an
+// interrupted scan can be resumed. With resume, Run carries on from the
+// checkpoint left there by an unfinished scan with the same settings (see
+// FindCheckpoint), if there is one, instead of starting over.
+func (s *Scanner) SetCheckpoint(dir string, resume bool) {
+	s.ckpt = newCheckpoint(dir, s.settings)
+	s.resume = resume
+}
+
+// SetReadRate changes the combined read rate limit of the scan, in bytes per
+// second; 0 removes it. It may be called while Run is in progress.
+func (s *Scanner) SetReadRate(bytesPerSecond int64) {
+	s.limiter.setRate(bytesPerSecond)
+}
 
-	// Stage 2: Group by size — discard unique sizes
-	sizeGroups := GroupBySize(files)
-	candidates := flattenGroups(sizeGroups)
-	if len(candidates) == 0 {
+// SetCache makes the scanner reuse and record hashes in c. Passing nil
+// disables caching.
+func (s *Scanner) SetCache(c *HashCache) {
+	s.cache = c
+}
+
+// Run executes the full deduplication pipeline and returns duplicate groups.
+// Walked files stream into size buckets, and the hashing stages then run
+// concurrently, passing groups along channels, so memory grows with the
+// number of candidates rather than with every file walked. When similar
+// images are wanted, every walked image is also kept and hashed by a separate
+// branch, since resized or re-encoded copies differ in size and content.
+func (s *Scanner) Run(ctx context.Context) ([]models.DuplicateGroup, error) {
+	// Persist whatever was hashed, even if the scan is cancelled part-way.
+	// The cache is only an optimisation, so a failed save is not fatal.
+	defer func() { _ = s.cache.Save() }()
+
+	// Stages 1-2: Walk directories, or carry on from a checkpoint
+	s.stats = models.ScanStats{}
+	s.skipped.reset()
+	s.undecoded.Store(0)
+	defer func() { s.stats.FilesSkipped = len(s.skipped.list()) }()
+	similar := s.settings.SimilarityThreshold > 0
+	var w walkResult
+	resumed := false
+	if s.resume {
+		var err error
+		if w, resumed, err = s.restore(ctx); err != nil {
+			return nil, err
+		}
+	}
+	if !resumed {
+		var err error
+		if w, err = s.walk(ctx); err != nil {
+			return nil, err
+		}
+		_ = s.ckpt.saveWalk(s.stats.FilesScanned, w)
+	}
+	if len(w.groups) == 0 && len(w.images) == 0 {
+		s.ckpt.remove()
 		return nil, nil
 	}
-
-	// Stage 3: Partial hash
-	s.onProgress("partial-hashing", 0, len(candidates))
-	candidates, err = PartialHash(ctx, candidates)
-	if err != nil {
-		return nil, fmt.Errorf("partial hash: %w", err)
+	bySize, images, links := w.groups, w.images, w.links
+	candidates := 0
+	for _, files := range bySize {
+		candidates += len(files)
 	}
-	s.onProgress("partial-hashing", len(candidates), len(candidates))
 
-	if err := ctx.Err(); err != nil {
-		return nil, err
-	}
+	// Stages 3-5: partial and full hashing of candidates, and perceptual
+	// hashing of images, run concurrently; each group moves on as soon as its
+	// files are hashed. Their reads share one scheduler, limited per device.
+	s.io = newIOScheduler(s.settings.IOLimits, s.settings.IdleIOPriority)
+	rd := &scanReads{limiter: s.limiter, meter: s.meter}
+	s.meter.setStages(hashStages(similar)...)
+	stopReports := s.reportEvery(progressInterval)
+	stopCheckpoints := s.ckpt.saveEvery(checkpointInterval)
+	g, pctx := errgroup.WithContext(ctx)
+	sized := make(chan []models.FileInfo)
+	partial := make(chan []models.FileInfo)
+	full := make(chan []models.FileInfo)
+	unhashed := make(chan []models.FileInfo)
+	hashed := make(chan []models.FileInfo)
+
+	g.Go(func() error {
+		defer close(sized)
+		for i, files := range bySize {
+			bySize[i] = nil // the pipeline owns the group from here on
+			select {
+			case sized <- files:
+			case <-pctx.Done():
+				return pctx.Err()
+			}
+		}
+		return nil
+	})
 
-	// Stage 4: Group by partial hash — discard unique partial hashes
-	partialGroups := GroupByHash(candidates, func(f models.FileInfo) string {
-		return f.PartialHash
+	// Stage 3: Partial hash, splitting groups by it
+	s.progress("partial-hashing", 0, candidates)
+	g.Go(func() error {
+		return s.runStage(pctx, hashStage{
+			name: "partial-hashing", kind: hashPartial, regroup: true,
+			hash: func(f models.FileInfo, _ func()) (string, error) { return computePartialHash(pctx, rd, f.Path, f.Size) },
+		}, sized, partial)
 	})
-	candidates = flattenGroups(partialGroups)
-	if len(candidates) == 0 {
-		return nil, nil
-	}
 
-	// Stage 5: Full hash
-	s.onProgress("full-hashing", 0, len(candidates))
-	candidates, err = FullHash(ctx, candidates)
-	if err != nil {
-		return nil, fmt.Errorf("full hash: %w", err)
-	}
-	s.onProgress("full-hashing", len(candidates), len(candidates))
+	// Stage 4: Full hash, splitting groups into exact duplicates
+	g.Go(func() error {
+		return s.runStage(pctx, hashStage{
+			name: "full-hashing", kind: hashFull, regroup: true,
+			hash: func(f models.FileInfo, _ func()) (string, error) { return computeFullHash(pctx, rd, f.Path) },
+		}, partial, full)
+	})
 
-	if err := ctx.Err(); err != nil {
-		return nil, err
+	// Stage 5: Perceptual hash of every image, each sent as its own group
+	if similar {
+		g.Go(func() error {
+			defer close(unhashed)
+			for i := range images {
+				select {
+				case unhashed <- images[i : i+1]:
+				case <-pctx.Done():
+					return pctx.Err()
+				}
+			}
+			return nil
+		})
+		s.progress("perceptual-hashing", 0, len(images))
+		algo, transforms := s.hashAlgorithm(), s.settings.MatchTransforms
+		cpus := make(chan struct{}, runtime.NumCPU())
+		g.Go(func() error {
+			return s.runStage(pctx, hashStage{
+				name: "perceptual-hashing", kind: hashPerceptual,
+				hash: func(f models.FileInfo, release func()) (string, error) {
+					data, err := readImage(pctx, rd, f.Path)
+					if err != nil {
+						return "", err
+					}
+					// Decoding needs no disk, so the device slot goes to the
+					// next read once a CPU is free to decode this image; while
+					// every CPU is busy, reads wait rather than pile up.
+					select {
+					case cpus <- struct{}{}:
+					case <-pctx.Done():
+						return "", pctx.Err()
+					}
+					defer func() { <-cpus }()
+					release()
+					return computePerceptualHash(data, algo, transforms)
+				},
+			}, unhashed, hashed)
+		})
+	} else {
+		close(hashed)
 	}
 
-	// Stage 5.5: Perceptual hash for images (enrichment, not filtering)
-	s.onProgress("perceptual-hashing", 0, len(candidates))
-	candidates, err = PerceptualHash(ctx, candidates)
-	if err != nil {
-		return nil, fmt.Errorf("perceptual hash: %w", err)
-	}
-	s.onProgress("perceptual-hashing", len(candidates), len(candidates))
+	// Stage 6: Collect exact duplicate groups and hashed images
+	var result []models.DuplicateGroup
+	g.Go(func() error {
+		for files := range full {
+			result = append(result, newExactGroup(files))
+		}
+		return nil
+	})
+	var decoded []models.FileInfo
+	g.Go(func() error {
+		for files := range hashed {
+			for _, f := range files {
+				if f.PerceptualHash == "" {
+					continue // unreadable or undecodable, and listed as skipped
+				}
+				s.stats.ImagesHashed++
+				decoded = append(decoded, f)
+			}
+		}
+		return nil
+	})
 
-	if err := ctx.Err(); err != nil {
+	err := g.Wait()
+	s.stats.UndecodableImages = int(s.undecoded.Load())
+	stopReports()
+	stopCheckpoints()
+	if err != nil {
 		return nil, err
 	}
 
-	// Stage 6: Group by full hash — build duplicate groups
-	fullGroups := GroupByHash(candidates, func(f models.FileInfo) string {
-		return f.FullHash
-	})
-
 	// Stage 7: Find similar images by perceptual hash
-	if s.settings.SimilarityThreshold > 0 {
-		similarGroups := groupBySimilarHash(candidates, int(s.settings.SimilarityThreshold))
-		result := buildDuplicateGroups(fullGroups)
-		result = append(result, buildSimilarGroups(similarGroups, s.settings.SimilarityThreshold)...)
-		return result, nil
+	if similar {
+		similarGroups, err := groupBySimilarHash(ctx, uniqueImages(result, decoded), int(s.settings.SimilarityThreshold), s.settings.ClusterMode, s.settings.MatchTransforms)
+		if err != nil {
+			return nil, fmt.Errorf("similar images: %w", err)
+		}
+		result = append(result, buildSimilarGroups(similarGroups)...)
 	}
 
-	return buildDuplicateGroups(fullGroups), nil
+	s.ckpt.remove()
+	expandHardLinks(result, links)
+	return dropReferenceOnly(result), nil
 }
 
-// flattenGroups collects all files from a map of groups into a single slice.
-func flattenGroups[K comparable](groups map[K][]models.FileInfo) []models.FileInfo {
-	var out []models.FileInfo
-	for _, group := range groups {
-		out = append(out, group...)
+// hashStages returns the names of the hashing stages a scan runs, in
+// pipeline order.
+func hashStages(similar bool) []string {
+	if similar {
+		return []string{"partial-hashing", "full-hashing", "perceptual-hashing"}
 	}
-	return out
+	return []string{"partial-hashing", "full-hashing"}
 }
 
-// buildDuplicateGroups converts hash groups into DuplicateGroup structs.
-func buildDuplicateGroups(groups map[string][]models.FileInfo) []models.DuplicateGroup {
-	result := make([]models.DuplicateGroup, 0, len(groups))
+// walkResult is what the hashing stages start from: groups of candidates
+// sharing a size, largest first, and the images to hash for similarity, with
+// one path per inode. The other hard links are kept aside by inode, and the
+// paths the walk could not examine are listed in skipped.
+type walkResult struct {
+	groups  [][]models.FileInfo
+	images  []models.FileInfo
+	links   map[inodeKey][]models.FileInfo
+	skipped []models.SkippedFile
+}
 
-	for _, files := range groups {
-		if len(files) < 2 {
-			continue
-		}
+// walk runs stages 1 and 2: it walks the scan roots, streaming files into
+// size buckets and collecting images, then keeps the sizes shared by two or
+// more files.
+func (s *Scanner) walk(ctx context.Context) (walkResult, error) {
+	s.progress("walking", 0, 0)
+	similar := s.settings.SimilarityThreshold > 0
+	sizes := newSizeIndex()
+	var images []models.FileInfo
+	err := Walk(ctx, s.settings.ScanRoots(), s.settings.MinFileSizeBytes(), s.settings.ExcludedDirs, s.settings.SkipHidden,
+		func(f models.FileInfo) {
+			// Blocking here stalls every walker, as Walk serialises calls
+			_ = s.limiter.hold(ctx)
+			s.stats.FilesScanned++
+			sizes.add(f)
+			if similar && isImage(f) {
+				images = append(images, f)
+			}
+		},
+		func(path string, err error) { s.skipped.add(path, "walking", err) })
+	if err != nil {
+		return walkResult{}, fmt.Errorf("walk: %w", err)
+	}
 
-		var totalSize int64
-		for _, f := range files {
-			totalSize += f.Size
+	// The first file of each size seen twice was not kept, so walk again
+	// for those. Paths it cannot read were already listed as skipped.
+	if sizes.unresolved() {
+		resolve := sizes.resolver()
+		err := Walk(ctx, s.settings.ScanRoots(), s.settings.MinFileSizeBytes(), s.settings.ExcludedDirs, s.settings.SkipHidden,
+			func(f models.FileInfo) {
+				_ = s.limiter.hold(ctx)
+				resolve(f)
+			}, nil)
+		if err != nil {
+			return walkResult{}, fmt.Errorf("walk: %w", err)
 		}
-		wastedSize := totalSize - files[0].Size // all copies minus one
+	}
+	s.progress("walking", s.stats.FilesScanned, s.stats.FilesScanned)
 
-		result = append(result, models.DuplicateGroup{
-			ID:         uuid.New().String(),
-			Kind:       models.KindExact,
-			Similarity: 0,
-			Files:      files,
-			TotalSize:  totalSize,
-			WastedSize: wastedSize,
-		})
+	if err := ctx.Err(); err != nil {
+		return walkResult{}, err
 	}
 
-	return result
+	// Hard links share their data, so only one path per inode goes through
+	// hashing; the others are restored into the final groups.
+	var w walkResult
+	var imageLinks map[inodeKey][]models.FileInfo
+	w.groups, w.links = sizes.groups()
+	w.images, imageLinks = collapseHardLinks(images)
+	for k, paths := range imageLinks {
+		if _, ok := w.links[k]; !ok {
+			w.links[k] = paths
+		}
+	}
+	for _, files := range w.groups {
+		markReadOnly(files, s.settings.ReferencePaths)
+	}
+	markReadOnly(w.images, s.settings.ReferencePaths)
+	for _, paths := range w.links {
+		markReadOnly(paths, s.settings.ReferencePaths)
+	}
+	w.skipped = s.skipped.list()
+	return w, nil
 }
 
-// groupBySimilarHash groups images with similar perceptual hashes.
-// distance is the maximum Hamming distance to consider as similar.
-func groupBySimilarHash(files []models.FileInfo, distance int) [][]models.FileInfo {
-	// Collect only files with perceptual hashes
-	var images []models.FileInfo
-	for _, f := range files {
-		if f.PerceptualHash != "" {
-			images = append(images, f)
+// restore loads the walk and hashes saved by an unfinished scan with the same
+// settings. Files that changed or disappeared since then are dropped, as
+// their hashes, and for candidates their size group, may no longer hold; new
+// files are not picked up. It reports false if there is no such checkpoint.
+func (s *Scanner) restore(ctx context.Context) (walkResult, bool, error) {
+	saved, hdr, ok := s.ckpt.load()
+	if !ok {
+		return walkResult{}, false, nil
+	}
+	total := len(saved.Images) + len(saved.Links)
+	for _, files := range saved.Groups {
+		total += len(files)
+	}
+	s.progress("resuming", 0, total)
+	for _, f := range saved.Skipped {
+		s.skipped.addReason(f.Path, f.Stage, f.Reason, f.Error)
+	}
+
+	checked := 0
+	keep := func(files []models.FileInfo) []models.FileInfo {
+		kept := files[:0]
+		for _, f := range files {
+			checked++
+			cur, err := StatFile(f.Path)
+			if err != nil {
+				s.skipped.add(f.Path, "resuming", err)
+				continue
+			}
+			if cur.Size != f.Size || cur.ModifiedNano != f.ModifiedNano || cur.Inode != f.Inode {
+				s.skipped.addReason(f.Path, "resuming", models.SkipChanged, "modified since the checkpoint was saved")
+				continue
+			}
+			h := s.ckpt.hashesFor(f.Path)
+			f.PartialHash, f.FullHash, f.PerceptualHash = h.Partial, h.Full, h.Perceptual
+			kept = append(kept, f)
 		}
+		return kept
 	}
 
-	if len(images) < 2 {
-		return nil
+	w := walkResult{links: make(map[inodeKey][]models.FileInfo)}
+	for _, files := range saved.Groups {
+		if err := ctx.Err(); err != nil {
+			return walkResult{}, false, err
+		}
+		if files = keep(files); len(files) >= 2 {
+			w.groups = append(w.groups, files)
+		}
+	}
+	w.images = keep(saved.Images)
+	for _, l := range keep(saved.Links) {
+		k := inodeKey{l.Device, l.Inode}
+		w.links[k] = append(w.links[k], l)
 	}
+	s.progress("resuming", checked, total)
 
-	// Parse hashes
-	type hashedImage struct {
-		file models.FileInfo
-		hash uint64
+	s.stats.FilesScanned = hdr.FilesScanned
+	return w, true, nil
+}
+
+// uniqueImages returns the hashed images with exact duplicates reduced to the
+// first file of their group, so a set of identical copies is reported once as
+// an exact group rather than again as similar images. The perceptual hashes
+// are also copied onto the files of the exact groups.
+func uniqueImages(exact []models.DuplicateGroup, images []models.FileInfo) []models.FileInfo {
+	if len(exact) == 0 {
+		return images
 	}
-	var hashed []hashedImage
-	for _, img := range images {
-		h, err := strconv.ParseUint(img.PerceptualHash, 16, 64)
-		if err != nil {
-			continue
+	hashes := make(map[string]string, len(images))
+	for _, f := range images {
+		hashes[f.Path] = f.PerceptualHash
+	}
+	copies := make(map[string]bool)
+	for _, g := range exact {
+		for i := range g.Files {
+			g.Files[i].PerceptualHash = hashes[g.Files[i].Path]
+			if i > 0 {
+				copies[g.Files[i].Path] = true
+			}
 		}
-		hashed = append(hashed, hashedImage{file: img, hash: h})
 	}
 
-	// Simple O(n^2) comparison — fine for typical image counts
-	used := make(map[int]bool)
-	var groups [][]models.FileInfo
-
-	for i := 0; i < len(hashed); i++ {
-		if used[i] {
-			continue
+	unique := images[:0]
+	for _, f := range images {
+		if !copies[f.Path] {
+			unique = append(unique, f)
 		}
-		group := []models.FileInfo{hashed[i].file}
-		for j := i + 1; j < len(hashed); j++ {
-			if used[j] {
+	}
+	return unique
+}
+
+// progress reports to the ProgressCallback. Stages run concurrently, so calls
+// are serialised here.
+func (s *Scanner) progress(stage string, processed, total int) {
+	s.progressMu.Lock()
+	defer s.progressMu.Unlock()
+	s.last = models.ScanProgress{Stage: stage, Processed: processed, Total: total, Paused: s.limiter.isPaused()}
+	s.meter.fill(&s.last, time.Now())
+	s.onProgress(s.last)
+}
+
+// reportEvery reports the progress of the earliest unfinished hashing stage
+// every interval, whenever it has moved on, until the returned function is
+// called.
+func (s *Scanner) reportEvery(interval time.Duration) (stop func()) {
+	done := make(chan struct{})
+	stopped := make(chan struct{})
+	go func() {
+		defer close(stopped)
+		t := time.NewTicker(interval)
+		defer t.Stop()
+		var lastBytes, lastFiles int64 = -1, -1
+		for {
+			select {
+			case <-done:
+				return
+			case <-t.C:
+			}
+			st := s.meter.front()
+			if st == nil {
 				continue
 			}
-			if hammingDistance(hashed[i].hash, hashed[j].hash) <= distance {
-				group = append(group, hashed[j].file)
-				used[j] = true
+			bytes, files := s.meter.done.Load(), st.done.Load()
+			if bytes == lastBytes && files == lastFiles {
+				continue
 			}
+			lastBytes, lastFiles = bytes, files
+			s.progress(st.name, int(files), int(st.queued.Load()))
 		}
-		if len(group) >= 2 {
-			used[i] = true
-			groups = append(groups, group)
-		}
+	}()
+	return func() {
+		close(done)
+		<-stopped
 	}
-
-	return groups
 }
 
-func hammingDistance(a, b uint64) int {
-	xor := a ^ b
-	count := 0
-	for xor != 0 {
-		count++
-		xor &= xor - 1
+// markReadOnly flags files under any of the reference roots.
+func markReadOnly(files []models.FileInfo, roots []string) {
+	if len(roots) == 0 {
+		return
+	}
+	for i := range files {
+		files[i].ReadOnly = models.IsUnderAny(files[i].Path, roots)
 	}
-	return count
 }
 
-func buildSimilarGroups(groups [][]models.FileInfo, threshold float64) []models.DuplicateGroup {
-	result := make([]models.DuplicateGroup, 0, len(groups))
-	for _, files := range groups {
-		var totalSize int64
-		for _, f := range files {
-			totalSize += f.Size
+// dropReferenceOnly removes groups in which every file is read-only, since
+// there is nothing in them the user could act on.
+func dropReferenceOnly(groups []models.DuplicateGroup) []models.DuplicateGroup {
+	kept := groups[:0]
+	for _, g := range groups {
+		for _, f := range g.Files {
+			if !f.ReadOnly {
+				kept = append(kept, g)
+				break
+			}
 		}
-		wastedSize := totalSize - files[0].Size
+	}
+	return kept
+}
 
-		result = append(result, models.DuplicateGroup{
-			ID:         uuid.New().String(),
-			Kind:       models.KindSimilar,
-			Similarity: 100 - threshold, // rough approximation
-			Files:      files,
-			TotalSize:  totalSize,
-			WastedSize: wastedSize,
-		})
+// newExactGroup builds a DuplicateGroup from files with the same full hash.
+func newExactGroup(files []models.FileInfo) models.DuplicateGroup {
+	g := models.DuplicateGroup{
+		ID:         uuid.New().String(),
+		Kind:       models.KindExact,
+		Similarity: 0,
+		Files:      files,
+	}
+	g.Recalculate()
+	return g
+}
+
+func buildSimilarGroups(clusters []similarCluster) []models.DuplicateGroup {
+	result := make([]models.DuplicateGroup, 0, len(clusters))
+	for _, c := range clusters {
+		g := models.DuplicateGroup{
+			ID:          uuid.New().String(),
+			Kind:        models.KindSimilar,
+			Similarity:  100,
+			MaxDistance: c.maxDistance,
+			Files:       c.files,
+		}
+		for _, f := range c.files {
+			g.Similarity = min(g.Similarity, f.Similarity)
+		}
+		g.Recalculate()
+		result = append(result, g)
 	}
 	return result
 }
@@ -0,0 +1,74 @@
+package scanner
+
+import (
+	"context"
+	"errors"
+	"io/fs"
+	"sort"
+	"sync"
+
+	"folder-cleaner-go/models"
+)
+
+// errUndecodable marks an image that was read but could not be decoded.
+var errUndecodable = errors.New("image could not be decoded")
+
+// skipReason classifies the error that made a scan leave a file out.
+func skipReason(err error) models.SkipReason {
+	switch {
+	case errors.Is(err, errUndecodable):
+		return models.SkipUndecodable
+	case errors.Is(err, fs.ErrPermission):
+		return models.SkipPermission
+	case errors.Is(err, fs.ErrNotExist):
+		return models.SkipVanished
+	default:
+		return models.SkipIOError
+	}
+}
+
+// skipList collects the files a scan left out, keeping the first reason
+// given for each path. It is safe for concurrent use.
+type skipList struct {
+	mu    sync.Mutex
+	files map[string]models.SkippedFile
+}
+
+// add records that path was left out in stage because of err. Errors from
+// cancelling the scan are ignored, as the file was not at fault.
+func (l *skipList) add(path, stage string, err error) {
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return
+	}
+	l.addReason(path, stage, skipReason(err), err.Error())
+}
+
+func (l *skipList) addReason(path, stage string, reason models.SkipReason, msg string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.files == nil {
+		l.files = make(map[string]models.SkippedFile)
+	}
+	if _, ok := l.files[path]; !ok {
+		l.files[path] = models.SkippedFile{Path: path, Reason: reason, Stage: stage, Error: msg}
+	}
+}
+
+// list returns the skipped files sorted by path.
+func (l *skipList) list() []models.SkippedFile {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	out := make([]models.SkippedFile, 0, len(l.files))
+	for _, f := range l.files {
+		out = append(out, f)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Path < out[j].Path })
+	return out
+}
+
+// reset empties the list for a new scan.
+func (l *skipList) reset() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.files = nil
+}
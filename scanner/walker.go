@@ -13,15 +13,23 @@ import (
 	"github.com/charlievieth/fastwalk"
 )
 
-// Walk traverses the given directories using fastwalk and returns file metadata.
-// It filters by minSize (bytes), skips directories in excludedDirs (matched by
-// base name), and optionally skips hidden files/dirs. Symlinks and zero-byte
-// files are always skipped.
-func Walk(ctx context.Context, paths []string, minSize int64, excludedDirs []string, skipHidden bool) ([]models.FileInfo, error) {
-	var (
-		mu    sync.Mutex
-		files []models.FileInfo
-	)
+// Walk traverses the given directories using fastwalk and passes the metadata
+// of each file to fn as it is found, so callers decide what to keep. fn is
+// called from one goroutine at a time. It filters by minSize (bytes), skips
+// directories in excludedDirs (matched by base name), and optionally skips
+// hidden files/dirs. Symlinks and zero-byte files are always skipped. Files
+// and directories that cannot be listed or stat'd are passed to onError, if
+// not nil, with the error, and the walk carries on without them; onError is
+// called under the same lock as fn.
+func Walk(ctx context.Context, paths []string, minSize int64, excludedDirs []string, skipHidden bool, fn func(models.FileInfo), onError func(path string, err error)) error {
+	var mu sync.Mutex
+	skip := func(path string, err error) {
+		if onError != nil {
+			mu.Lock()
+			onError(path, err)
+			mu.Unlock()
+		}
+	}
 
 	// Build lookup set for excluded directory base names
 	excludedSet := make(map[string]bool, len(excludedDirs))
@@ -41,12 +49,13 @@ func Walk(ctx context.Context, paths []string, minSize int64, excludedDirs []str
 
 	for _, root := range paths {
 		if err := ctx.Err(); err != nil {
-			return nil, err
+			return err
 		}
 
 		err := fastwalk.Walk(&conf, root, func(path string, d os.DirEntry, err error) error {
 			if err != nil {
-				return nil // skip files/dirs we can't access
+				skip(path, err) // skip files/dirs we can't access
+				return nil
 			}
 
 			// Check cancellation
@@ -80,7 +89,8 @@ func Walk(ctx context.Context, paths []string, minSize int64, excludedDirs []str
 
 			info, err := d.Info()
 			if err != nil {
-				return nil // skip if we can't stat
+				skip(path, err) // skip if we can't stat
+				return nil
 			}
 
 			// Skip files below minimum size
@@ -88,24 +98,42 @@ func Walk(ctx context.Context, paths []string, minSize int64, excludedDirs []str
 				return nil
 			}
 
-			f := models.FileInfo{
-				Path:      path,
-				Name:      name,
-				Extension: strings.ToLower(strings.TrimPrefix(filepath.Ext(name), ".")),
-				Size:      info.Size(),
-				Modified:  info.ModTime().Unix(),
-			}
+			f := newFileInfo(path, info)
 
 			mu.Lock()
-			files = append(files, f)
+			fn(f)
 			mu.Unlock()
 
 			return nil
 		})
 		if err != nil {
-			return nil, err
+			return err
 		}
 	}
 
-	return files, nil
+	return nil
+}
+
+// StatFile returns the metadata for a single file in the same form Walk
+// produces, e.g. to re-add a restored file to its duplicate group.
+func StatFile(path string) (models.FileInfo, error) {
+	info, err := os.Lstat(path)
+	if err != nil {
+		return models.FileInfo{}, err
+	}
+	return newFileInfo(path, info), nil
+}
+
+func newFileInfo(path string, info os.FileInfo) models.FileInfo {
+	dev, ino := fileID(info)
+	return models.FileInfo{
+		Path:         path,
+		Name:         info.Name(),
+		Extension:    strings.ToLower(strings.TrimPrefix(filepath.Ext(info.Name()), ".")),
+		Size:         info.Size(),
+		Modified:     info.ModTime().Unix(),
+		ModifiedNano: info.ModTime().UnixNano(),
+		Device:       dev,
+		Inode:        ino,
+	}
 }
@@ -0,0 +1,151 @@
+// Package selection proposes which files of each duplicate group to remove,
+// based on an ordered list of keep rules.
+package selection
+
+import (
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"folder-cleaner-go/models"
+)
+
+// rule narrows a set of candidate files to those preferred for keeping.
+// Returning an empty slice means the rule expresses no preference.
+type rule struct {
+	name   string
+	narrow func(files []models.FileInfo) []models.FileInfo
+}
+
+// Propose applies rules to every group and returns, per group, the file to
+// keep and the files to remove. Groups are left untouched; nothing is deleted.
+func Propose(groups []models.DuplicateGroup, rules []models.SelectionRule) ([]models.Selection, error) {
+	compiled, err := compile(rules)
+	if err != nil {
+		return nil, err
+	}
+
+	out := make([]models.Selection, 0, len(groups))
+	for _, g := range groups {
+		if s, ok := selectGroup(g, compiled); ok {
+			out = append(out, s)
+		}
+	}
+	return out, nil
+}
+
+func selectGroup(g models.DuplicateGroup, rules []rule) (models.Selection, bool) {
+	// Only distinct copies are candidates; hard links follow their original
+	var candidates, reference []models.FileInfo
+	for _, f := range g.Files {
+		if f.HardLinkOf == "" {
+			candidates = append(candidates, f)
+			if f.ReadOnly {
+				reference = append(reference, f)
+			}
+		}
+	}
+	if len(candidates) < 2 {
+		return models.Selection{}, false
+	}
+
+	reason := "first by path"
+	// A reference copy is never removed, so one of them is always kept
+	if len(reference) > 0 {
+		candidates, reason = reference, "reference copy"
+	}
+	for _, r := range rules {
+		narrowed := r.narrow(candidates)
+		if len(narrowed) == 0 || len(narrowed) == len(candidates) {
+			continue
+		}
+		candidates = narrowed
+		reason = r.name
+		if len(candidates) == 1 {
+			break
+		}
+	}
+
+	// Deterministic tie-break
+	sort.Slice(candidates, func(i, j int) bool { return candidates[i].Path < candidates[j].Path })
+	keep := candidates[0]
+
+	s := models.Selection{GroupID: g.ID, Keep: keep.Path, Reason: reason}
+	for _, f := range g.Files {
+		// Removing a hard link of the kept file would free nothing
+		if f.Path == keep.Path || f.HardLinkOf == keep.Path || f.ReadOnly {
+			continue
+		}
+		s.Remove = append(s.Remove, f.Path)
+	}
+	return s, len(s.Remove) > 0
+}
+
+func compile(rules []models.SelectionRule) ([]rule, error) {
+	out := make([]rule, 0, len(rules))
+	for _, r := range rules {
+		name := string(r.Type)
+		switch r.Type {
+		case models.RuleKeepOldest:
+			out = append(out, rule{name, extremes(func(f models.FileInfo) int64 { return f.Modified }, false)})
+		case models.RuleKeepNewest:
+			out = append(out, rule{name, extremes(func(f models.FileInfo) int64 { return f.Modified }, true)})
+		case models.RuleKeepShortestPath:
+			out = append(out, rule{name, extremes(func(f models.FileInfo) int64 { return int64(len(f.Path)) }, false)})
+		case models.RuleKeepLongestPath:
+			out = append(out, rule{name, extremes(func(f models.FileInfo) int64 { return int64(len(f.Path)) }, true)})
+		case models.RuleKeepInDirectory:
+			if r.Value == "" {
+				return nil, fmt.Errorf("%s: directory is required", r.Type)
+			}
+			// A root such as "/" or `C:\` already ends in a separator
+			dir := filepath.Clean(r.Value)
+			if !strings.HasSuffix(dir, string(filepath.Separator)) {
+				dir += string(filepath.Separator)
+			}
+			out = append(out, rule{name + " " + r.Value, filter(func(f models.FileInfo) bool {
+				return strings.HasPrefix(f.Path, dir)
+			})})
+		case models.RuleKeepMatching, models.RuleAvoidMatching:
+			re, err := regexp.Compile(r.Value)
+			if err != nil {
+				return nil, fmt.Errorf("%s: %w", r.Type, err)
+			}
+			want := r.Type == models.RuleKeepMatching
+			out = append(out, rule{name + " " + r.Value, filter(func(f models.FileInfo) bool {
+				return re.MatchString(f.Path) == want
+			})})
+		default:
+			return nil, fmt.Errorf("unknown selection rule %q", r.Type)
+		}
+	}
+	return out, nil
+}
+
+// extremes keeps the files with the smallest (or largest) key.
+func extremes(key func(models.FileInfo) int64, largest bool) func([]models.FileInfo) []models.FileInfo {
+	return func(files []models.FileInfo) []models.FileInfo {
+		best := key(files[0])
+		for _, f := range files[1:] {
+			if k := key(f); (largest && k > best) || (!largest && k < best) {
+				best = k
+			}
+		}
+		return filter(func(f models.FileInfo) bool { return key(f) == best })(files)
+	}
+}
+
+// filter keeps the files satisfying keep.
+func filter(keep func(models.FileInfo) bool) func([]models.FileInfo) []models.FileInfo {
+	return func(files []models.FileInfo) []models.FileInfo {
+		var out []models.FileInfo
+		for _, f := range files {
+			if keep(f) {
+				out = append(out, f)
+			}
+		}
+		return out
+	}
+}
@@ -0,0 +1,196 @@
+package selection
+
+import (
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"folder-cleaner-go/models"
+)
+
+// file builds a FileInfo from a slash-separated path.
+func file(path string, modified int64) models.FileInfo {
+	return models.FileInfo{Path: filepath.FromSlash(path), Modified: modified}
+}
+
+func paths(ps ...string) []string {
+	out := make([]string, len(ps))
+	for i, p := range ps {
+		out[i] = filepath.FromSlash(p)
+	}
+	return out
+}
+
+func TestPropose(t *testing.T) {
+	group := models.DuplicateGroup{ID: "g", Files: []models.FileInfo{
+		file("/photos/2020/a.jpg", 300),
+		file("/backup/a.jpg", 100),
+		file("/photos/a copy.jpg", 200),
+	}}
+
+	tests := []struct {
+		name   string
+		rules  []models.SelectionRule
+		keep   string
+		reason string
+	}{
+		{"no rules", nil, "/backup/a.jpg", "first by path"},
+		{"oldest", []models.SelectionRule{{Type: models.RuleKeepOldest}}, "/backup/a.jpg", "keep_oldest"},
+		{"newest", []models.SelectionRule{{Type: models.RuleKeepNewest}}, "/photos/2020/a.jpg", "keep_newest"},
+		{"shortest path", []models.SelectionRule{{Type: models.RuleKeepShortestPath}}, "/backup/a.jpg", "keep_shortest_path"},
+		{"longest path", []models.SelectionRule{{Type: models.RuleKeepLongestPath}}, "/photos/2020/a.jpg", "keep_longest_path"},
+		{"in directory", []models.SelectionRule{{Type: models.RuleKeepInDirectory, Value: "/photos/"}}, "/photos/2020/a.jpg", "keep_in_directory /photos/"},
+		{"in directory then oldest", []models.SelectionRule{
+			{Type: models.RuleKeepInDirectory, Value: "/photos"},
+			{Type: models.RuleKeepOldest},
+		}, "/photos/a copy.jpg", "keep_oldest"},
+		{"matching", []models.SelectionRule{{Type: models.RuleKeepMatching, Value: `copy`}}, "/photos/a copy.jpg", "keep_matching copy"},
+		{"avoid matching", []models.SelectionRule{{Type: models.RuleAvoidMatching, Value: `^/backup/|copy`}}, "/photos/2020/a.jpg", "avoid_matching ^/backup/|copy"},
+		{"rule matching every file is skipped", []models.SelectionRule{
+			{Type: models.RuleKeepMatching, Value: `a`},
+			{Type: models.RuleKeepNewest},
+		}, "/photos/2020/a.jpg", "keep_newest"},
+		{"rule matching no file is skipped", []models.SelectionRule{
+			{Type: models.RuleKeepInDirectory, Value: "/music"},
+		}, "/backup/a.jpg", "first by path"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if filepath.Separator != '/' && (tt.name == "matching" || tt.name == "avoid matching") {
+				t.Skip("regular expressions are written for slash-separated paths")
+			}
+			got, err := Propose([]models.DuplicateGroup{group}, tt.rules)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(got) != 1 {
+				t.Fatalf("got %d selections, want 1", len(got))
+			}
+			s := got[0]
+			if s.Keep != filepath.FromSlash(tt.keep) || s.Reason != tt.reason {
+				t.Errorf("kept %s (%s), want %s (%s)", s.Keep, s.Reason, filepath.FromSlash(tt.keep), tt.reason)
+			}
+			if len(s.Remove) != 2 {
+				t.Errorf("remove = %v, want the other two files", s.Remove)
+			}
+			for _, r := range s.Remove {
+				if r == s.Keep {
+					t.Errorf("the kept file %s is also removed", r)
+				}
+			}
+		})
+	}
+}
+
+func TestKeepInDirectoryPrefix(t *testing.T) {
+	group := models.DuplicateGroup{ID: "g", Files: []models.FileInfo{
+		file("/photos-old/a.jpg", 0),
+		file("/photos/a.jpg", 0),
+	}}
+	tests := []struct {
+		dir  string
+		keep string
+	}{
+		// A sibling sharing the name as a prefix is not inside the directory
+		{"/photos", "/photos/a.jpg"},
+		{"/photos/", "/photos/a.jpg"},
+		{"/photos/../photos-old", "/photos-old/a.jpg"},
+		// The root holds everything, so it decides nothing
+		{"/", "/photos-old/a.jpg"},
+	}
+	for _, tt := range tests {
+		rules := []models.SelectionRule{{Type: models.RuleKeepInDirectory, Value: filepath.FromSlash(tt.dir)}, {Type: models.RuleKeepLongestPath}}
+		got, err := Propose([]models.DuplicateGroup{group}, rules)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(got) != 1 || got[0].Keep != filepath.FromSlash(tt.keep) {
+			t.Errorf("%s: got %+v, want to keep %s", tt.dir, got, tt.keep)
+		}
+	}
+}
+
+func TestKeepInRootDirectory(t *testing.T) {
+	// Every absolute path is under the root, so the rule keeps them all and
+	// the next rule decides
+	root := string(filepath.Separator)
+	rule := compileOne(t, models.SelectionRule{Type: models.RuleKeepInDirectory, Value: root})
+	files := []models.FileInfo{file("/a", 0), file("/b/c", 0)}
+	if got := rule.narrow(files); !reflect.DeepEqual(got, files) {
+		t.Errorf("root kept %v, want every file", got)
+	}
+}
+
+func compileOne(t *testing.T, r models.SelectionRule) rule {
+	t.Helper()
+	rules, err := compile([]models.SelectionRule{r})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return rules[0]
+}
+
+func TestProposeReferenceAndHardLinks(t *testing.T) {
+	group := models.DuplicateGroup{ID: "g", Files: []models.FileInfo{
+		file("/a/new.jpg", 200),
+		{Path: filepath.FromSlash("/a/new-link.jpg"), Modified: 200, HardLinkOf: filepath.FromSlash("/a/new.jpg")},
+		{Path: filepath.FromSlash("/ref/old.jpg"), Modified: 100, ReadOnly: true},
+		file("/b/old.jpg", 100),
+	}}
+
+	// A reference copy is kept whatever the rules prefer, and never removed
+	got, err := Propose([]models.DuplicateGroup{group}, []models.SelectionRule{{Type: models.RuleKeepNewest}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := models.Selection{GroupID: "g", Keep: filepath.FromSlash("/ref/old.jpg"), Reason: "reference copy",
+		Remove: paths("/a/new.jpg", "/a/new-link.jpg", "/b/old.jpg")}
+	if len(got) != 1 || !reflect.DeepEqual(got[0], want) {
+		t.Errorf("got %+v, want %+v", got, want)
+	}
+
+	// Without one, hard links of the kept file are not removed with it
+	group.Files[2].ReadOnly = false
+	got, err = Propose([]models.DuplicateGroup{group}, []models.SelectionRule{{Type: models.RuleKeepNewest}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want = models.Selection{GroupID: "g", Keep: filepath.FromSlash("/a/new.jpg"), Reason: "keep_newest",
+		Remove: paths("/ref/old.jpg", "/b/old.jpg")}
+	if len(got) != 1 || !reflect.DeepEqual(got[0], want) {
+		t.Errorf("got %+v, want %+v", got, want)
+	}
+}
+
+func TestProposeSkipsGroupsWithNothingToRemove(t *testing.T) {
+	groups := []models.DuplicateGroup{
+		{ID: "links", Files: []models.FileInfo{
+			file("/a", 0),
+			{Path: filepath.FromSlash("/b"), HardLinkOf: filepath.FromSlash("/a")},
+		}},
+		{ID: "references", Files: []models.FileInfo{
+			{Path: filepath.FromSlash("/ref/a"), ReadOnly: true},
+			{Path: filepath.FromSlash("/ref/b"), ReadOnly: true},
+		}},
+	}
+	got, err := Propose(groups, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 0 {
+		t.Errorf("got %+v, want no selections", got)
+	}
+}
+
+func TestCompileErrors(t *testing.T) {
+	for _, r := range []models.SelectionRule{
+		{Type: models.RuleKeepInDirectory},
+		{Type: models.RuleKeepMatching, Value: "("},
+		{Type: models.RuleAvoidMatching, Value: "[a-"},
+		{Type: "keep_largest"},
+	} {
+		if _, err := Propose(nil, []models.SelectionRule{r}); err == nil {
+			t.Errorf("%+v: expected an error", r)
+		}
+	}
+}